@@ -0,0 +1,11 @@
+// Command jul executes Jul code locally.
+//
+//	jul                                          start the REPL (or execute the code piped to stdin)
+//	jul file.ju                                  execute a file
+//	jul test [paths]                             run the tests found in files or directories
+//	jul transcript [-seed n] file.ju transcript  check a conversation against a transcript
+package main
+
+import "github.com/ejuju/jus/pkg/jul"
+
+func main() { jul.RunCLI() }
@@ -1,5 +1,26 @@
 package main
 
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/ejuju/jus/pkg/juhttp"
+)
+
+// Serves the web-based UI on port 8081 for the echo server example (running on port 8080).
+// Use "-ui wasm" to serve the host page of the WebAssembly build instead.
 func main() {
-	panic("todo")
+	ui := flag.String("ui", "", "directory of the web page to serve instead of the chat page")
+	flag.Parse()
+
+	gw := juhttp.NewGateway(&net.TCPAddr{Port: 8080})
+	if *ui != "" {
+		gw.UI = http.FileServer(http.Dir(*ui))
+	}
+	err := gw.Run(8081)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
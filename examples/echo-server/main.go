@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"time"
 
+	"github.com/ejuju/jus/pkg/jul"
 	"github.com/ejuju/jus/pkg/jutp"
 )
 
@@ -20,26 +26,53 @@ write
 `
 
 func main() {
+	srv := &jutp.Server{Addr: &net.TCPAddr{Port: 8080}, Handler: handle}
+
+	// Let active conversations end (for up to 10 seconds) when interrupted
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		<-interrupt
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
 	log.Println("starting echo server on port 8080")
-	jutp.Serve(&net.TCPAddr{Port: 8080}, func(rui *jutp.RemoteUI) {
-		err := rui.Exec(welcomeMessage)
+	err := srv.Serve(context.Background())
+	if !errors.Is(err, jutp.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-stopped
+}
+
+func handle(ctx context.Context, rui *jutp.RemoteUI) {
+	err := rui.Exec(welcomeMessage)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for {
+		msg, err := rui.Read()
 		if err != nil {
 			log.Println(err)
 			return
 		}
-
-		for {
-			msg, err := rui.Read()
-			if err != nil {
-				log.Println(err)
-				return
-			}
-			// msg = strings.ReplaceAll(msg, `"`, `\"`) // TODO: escape potential quote characters
-			err = rui.Exec(`"Received: ` + string(msg) + `\n" write`)
-			if err != nil {
-				log.Println(err)
-				return
-			}
+		code, err := jul.Format(`"Received: " %s add "\n" add write`, string(msg))
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		err = rui.Exec(code)
+		if err != nil {
+			log.Println(err)
+			return
 		}
-	})
+	}
 }
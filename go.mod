@@ -1,3 +1,7 @@
 module github.com/ejuju/jus
 
 go 1.18
+
+require golang.org/x/term v0.27.0
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -1,37 +1,244 @@
 package main
 
 import (
-	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ejuju/jus/pkg/juclient"
 	"github.com/ejuju/jus/pkg/jul"
 	"github.com/ejuju/jus/pkg/jutp"
 )
 
+// config holds the client settings, set from command-line flags or environment variables.
+type config struct {
+	host        string
+	port        string
+	tls         bool
+	tlsInsecure bool
+	storeDir    string
+	verbose     bool
+	trace       bool
+	maxSteps    int
+	maxMemory   int
+	tokens      jul.Store // Holds the session token sent by the server
+}
+
 func main() {
-	// Connect to remote server
-	conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{
-		IP:   net.IPv4(127, 0, 0, 1),
-		Port: 8080,
+	cfg, err := parseConfig(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = run(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parseConfig(args []string) (*config, error) {
+	cfg := &config{}
+	useTLS := false
+	if v := os.Getenv("JUS_TLS"); v != "" {
+		var err error
+		useTLS, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JUS_TLS value %q (must be true or false)", v)
+		}
+	}
+	fset := flag.NewFlagSet("jus", flag.ExitOnError)
+	fset.StringVar(&cfg.host, "host", envOr("JUS_HOST", "127.0.0.1"), "server host name or IP address (env JUS_HOST)")
+	fset.StringVar(&cfg.port, "port", envOr("JUS_PORT", "8080"), "server port (env JUS_PORT)")
+	fset.BoolVar(&cfg.tls, "tls", useTLS, "connect using TLS (env JUS_TLS)")
+	fset.BoolVar(&cfg.tlsInsecure, "tls-insecure", false, "don't verify the server certificate (for testing only)")
+	fset.StringVar(&cfg.storeDir, "store", os.Getenv("JUS_STORE"), "directory where client data is stored (env JUS_STORE)")
+	fset.BoolVar(&cfg.verbose, "v", false, "log connection events")
+	fset.BoolVar(&cfg.trace, "trace", false, "log every JuTP message sent and received")
+	fset.IntVar(&cfg.maxSteps, "max-steps", 10_000_000, "maximum number of instructions executed per message (0 means no limit)")
+	fset.IntVar(&cfg.maxMemory, "max-memory", 64<<20, "maximum memory used by the stack in bytes (0 means no limit)")
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "Usage: %s [flags]\n\nConnects to a JuTP server and executes the code it sends.\n\n", fset.Name())
+		fset.PrintDefaults()
+	}
+	_ = fset.Parse(args)
+	return cfg, nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// addr returns the server address, host names and IPv6 addresses are supported.
+func (cfg *config) addr() string { return net.JoinHostPort(strings.Trim(cfg.host, "[]"), cfg.port) }
+
+func (cfg *config) logf(format string, args ...any) {
+	if cfg.verbose {
+		log.Printf(format, args...)
+	}
+}
+
+// dial opens a connection to the server and sends the hello message.
+func (cfg *config) dial(script string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if cfg.tls {
+		conn, err = tls.DialWithDialer(dialer, "tcp", cfg.addr(), &tls.Config{InsecureSkipVerify: cfg.tlsInsecure})
+	} else {
+		conn, err = dialer.Dial("tcp", cfg.addr())
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg.logf("connected to %s", conn.RemoteAddr())
+	if cfg.trace {
+		conn = juclient.TraceConn(conn, log.Default())
+	}
+	token, err := cfg.token()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_, err = jutp.Write(conn, jutp.HelloWithToken(script, token))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func run(cfg *config) error {
+	// Open client store for this server
+	if cfg.storeDir == "" {
+		var err error
+		cfg.storeDir, err = jul.DefaultStoreDir()
+		if err != nil {
+			return err
+		}
+	}
+	store := jul.NewFileStore(cfg.storeDir, cfg.addr())
+	cfg.logf("using store directory %s", cfg.storeDir)
+
+	// The session token is presented when reconnecting so that the server resumes the conversation
+	cfg.tokens = jul.NewFileStore(filepath.Join(cfg.storeDir, "sessions"), cfg.addr())
+
+	// Capabilities granted to this server are saved along with the store
+	permissions := jul.NewPermissions(cfg.addr(), jul.NewFileStore(filepath.Join(cfg.storeDir, "permissions"), cfg.addr()))
+
+	// Log all data sent to this server
+	audit, err := jul.OpenAuditLog(filepath.Join(cfg.storeDir, "audit"), cfg.addr())
+	if err != nil {
+		return err
+	}
+	defer audit.Close()
+
+	opts := []jul.Option{
+		jul.WithOrigin(cfg.addr()),
+		jul.WithStore(store),
+		jul.WithPermissions(permissions),
+		jul.WithAuditLog(audit),
+		jul.WithMaxSteps(cfg.maxSteps),
+		jul.WithMaxMemory(cfg.maxMemory),
+	}
+
+	// Run scripts installed by this server
+	var scheduler *juclient.Scheduler
+	scripts := jul.NewFileStore(filepath.Join(cfg.storeDir, "scripts"), cfg.addr())
+	scheduler = juclient.NewScheduler(scripts, permissions, func(script juclient.Script) error {
+		return runScript(cfg, scheduler, script, opts...)
 	})
+	err = scheduler.Start()
 	if err != nil {
-		panic(err)
+		return err
+	}
+	defer scheduler.Stop()
+
+	// Connect to remote server
+	conn, err := cfg.dial("")
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
-	// Execute code received from server
-	vm := jul.NewVM(jul.WithServerConnection(conn))
-	r := bufio.NewReader(conn)
-	msg, err := jutp.Read(r)
+	// Execute code received from server until the connection is closed
+	session := juclient.NewSession(conn, jul.NewDefaultUI(nil, nil), cfg.handleControl(scheduler), opts...)
+	err = session.Run()
 	if err != nil {
-		log.Println(err)
-		return
+		return err
+	}
+	cfg.logf("connection closed by server")
+
+	// Keep running installed scripts until interrupted
+	if scheduler.Len() > 0 {
+		cfg.logf("running %d installed script(s), press Ctrl+C to exit", scheduler.Len())
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		<-interrupt
 	}
-	err = vm.Execute(strings.NewReader(string(msg)))
+	return nil
+}
+
+// handleControl saves the session token sent by the server, scripts are installed with the scheduler.
+func (cfg *config) handleControl(scheduler *juclient.Scheduler) func(jul.UI, jutp.Control) error {
+	return func(ui jul.UI, c jutp.Control) error {
+		if c.Command != jutp.CommandSession {
+			return scheduler.HandleControl(ui, c)
+		}
+		cfg.logf("received session token")
+		b, err := json.Marshal(c.Params["token"])
+		if err != nil {
+			return err
+		}
+		return cfg.tokens.Set("token", b)
+	}
+}
+
+// token returns the session token saved for the server, if any.
+func (cfg *config) token() (string, error) {
+	b, err := cfg.tokens.Get("token")
+	if errors.Is(err, jul.ErrMissingKey) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	var token string
+	err = json.Unmarshal(b, &token)
+	return token, err
+}
+
+// runScript executes an installed script with a fresh VM on a new connection,
+// messages sent back by the server are handled until the server closes the connection
+// (or until the script is due to run again).
+// Installed scripts run without the user: they can't read input and their uploads are refused.
+func runScript(cfg *config, scheduler *juclient.Scheduler, script juclient.Script, opts ...jul.Option) error {
+	cfg.logf("running installed script %q", script.Name)
+	conn, err := cfg.dial(script.Name)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.SetDeadline(time.Now().Add(script.Interval))
+	if err != nil {
+		return err
+	}
+
+	opts = append(opts[:len(opts):len(opts)], jul.WithTimeout(script.Interval))
+	session := juclient.NewSession(conn, juclient.NewBackgroundUI(os.Stdout), cfg.handleControl(scheduler), opts...)
+	err = session.VM.ExecuteNamed(script.Name, strings.NewReader(script.Code))
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return session.Run()
 }
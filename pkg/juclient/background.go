@@ -0,0 +1,29 @@
+package juclient
+
+import (
+	"errors"
+	"io"
+
+	"github.com/ejuju/jus/pkg/jul"
+)
+
+// ErrNoUserInput is returned when a script executed in the background asks for user input.
+var ErrNoUserInput = errors.New("user input is not available to background scripts")
+
+// BackgroundUI is the UI of installed scripts, which are executed without the user:
+// messages are written to the given writer, reads fail and uploads are refused.
+// Capabilities can't be granted either, only those granted beforehand are available.
+type BackgroundUI struct{ out *jul.DefaultUI }
+
+func NewBackgroundUI(w io.Writer) *BackgroundUI {
+	return &BackgroundUI{out: jul.NewDefaultUI(eofReader{}, w)}
+}
+
+func (ui *BackgroundUI) Write(msg string) error                 { return ui.out.Write(msg) }
+func (ui *BackgroundUI) WriteFormatted(msg jul.Message) error   { return ui.out.WriteFormatted(msg) }
+func (ui *BackgroundUI) Read() (string, error)                  { return "", ErrNoUserInput }
+func (ui *BackgroundUI) ConfirmUpload(jul.Upload) (bool, error) { return false, nil }
+
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) { return 0, io.EOF }
@@ -0,0 +1,44 @@
+package juclient
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ejuju/jus/pkg/jul"
+)
+
+func TestBackgroundUI(t *testing.T) {
+	tests := []struct {
+		desc string
+		code string
+		want error
+	}{
+		{desc: "writes messages", code: `"Hello" write`},
+		{desc: "refuses to read", code: `read`, want: ErrNoUserInput},
+		{desc: "refuses to ask", code: `"Pick one" {"a" "b"} ask-choice`, want: ErrNoUserInput},
+		{desc: "refuses uploads", code: `"Hello" retrieve`, want: jul.ErrUploadDenied},
+		{desc: "denies capabilities", code: `*k 1 store-set`, want: jul.ErrPermissionDenied},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			out := &strings.Builder{}
+			conn, _ := net.Pipe()
+			defer conn.Close()
+			permissions := jul.NewPermissions("example.com", nil)
+			err := permissions.Grant(jul.CapabilityNetwork)
+			if err != nil {
+				panic(err)
+			}
+			session := NewSession(conn, NewBackgroundUI(out), nil, jul.WithPermissions(permissions))
+			err = session.VM.Execute(strings.NewReader(test.code))
+			if !errors.Is(err, test.want) {
+				t.Fatalf("got error %v instead of %v", err, test.want)
+			}
+			if test.want == nil && out.String() != "Hello" {
+				t.Fatalf("got output %q instead of %q", out.String(), "Hello")
+			}
+		})
+	}
+}
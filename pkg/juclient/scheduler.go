@@ -0,0 +1,181 @@
+package juclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ejuju/jus/pkg/jul"
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+// MinInterval is the shortest interval allowed between two executions of an installed script.
+const MinInterval = 10 * time.Second
+
+// MaxScripts is the maximum number of scripts installed by a server.
+const MaxScripts = 16
+
+// Script is a script installed by a server and executed periodically by the client.
+type Script struct {
+	Name     string        `json:"name"`
+	Interval time.Duration `json:"interval"`
+	Code     string        `json:"code"`
+}
+
+// Scheduler persists the scripts installed by a server and executes them periodically.
+type Scheduler struct {
+	store       jul.Store
+	permissions *jul.Permissions
+	run         func(Script) error
+
+	mu    sync.Mutex
+	stops map[string]chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewScheduler returns a scheduler that persists scripts in the given store
+// and executes them with the given function.
+// The server needs the install capability to install scripts, unless permissions are nil.
+func NewScheduler(store jul.Store, permissions *jul.Permissions, run func(Script) error) *Scheduler {
+	return &Scheduler{store: store, permissions: permissions, run: run, stops: map[string]chan struct{}{}}
+}
+
+// Start schedules all scripts found in the store.
+func (s *Scheduler) Start() error {
+	names, err := s.store.Keys()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		b, err := s.store.Get(name)
+		if err != nil {
+			return err
+		}
+		var script Script
+		err = json.Unmarshal(b, &script)
+		if err != nil {
+			return fmt.Errorf("decode script %q: %w", name, err)
+		}
+		s.schedule(script)
+	}
+	return nil
+}
+
+// Stop stops executing scripts and waits for running executions to finish.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	for name, stop := range s.stops {
+		close(stop)
+		delete(s.stops, name)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Len returns the number of scheduled scripts.
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.stops)
+}
+
+// Install persists and schedules a script, replacing any script with the same name.
+func (s *Scheduler) Install(script Script) error {
+	if script.Name == "" {
+		return errors.New("missing script name")
+	}
+	if script.Interval < MinInterval {
+		return fmt.Errorf("interval %s is shorter than %s", script.Interval, MinInterval)
+	}
+	names, err := s.store.Keys()
+	if err != nil {
+		return err
+	}
+	if len(names) >= MaxScripts && !containsName(names, script.Name) {
+		return fmt.Errorf("can't install more than %d scripts", MaxScripts)
+	}
+	b, err := json.Marshal(script)
+	if err != nil {
+		return err
+	}
+	err = s.store.Set(script.Name, b)
+	if err != nil {
+		return err
+	}
+	s.schedule(script)
+	return nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Uninstall removes a script from the store and stops executing it.
+func (s *Scheduler) Uninstall(name string) error {
+	s.mu.Lock()
+	if stop, ok := s.stops[name]; ok {
+		close(stop)
+		delete(s.stops, name)
+	}
+	s.mu.Unlock()
+	return s.store.Delete(name)
+}
+
+// HandleControl installs or uninstalls a script as requested by the server,
+// the user is asked with the given UI the first time the server installs a script.
+func (s *Scheduler) HandleControl(ui jul.UI, c jutp.Control) error {
+	switch c.Command {
+	default:
+		return fmt.Errorf("%w: unexpected command %q", jutp.ErrInvalidControl, c.Command)
+	case jutp.CommandInstall:
+		interval, err := time.ParseDuration(c.Params["interval"])
+		if err != nil {
+			return fmt.Errorf("%w: %s", jutp.ErrInvalidControl, err)
+		}
+		if s.permissions != nil {
+			err = s.permissions.Check(ui, jul.CapabilityInstall)
+			if err != nil {
+				return fmt.Errorf("install script %q: %w", c.Params["name"], err)
+			}
+		}
+		return s.Install(Script{Name: c.Params["name"], Interval: interval, Code: c.Body})
+	case jutp.CommandUninstall:
+		return s.Uninstall(c.Params["name"])
+	}
+}
+
+func (s *Scheduler) schedule(script Script) {
+	stop := make(chan struct{})
+	s.mu.Lock()
+	if prev, ok := s.stops[script.Name]; ok {
+		close(prev)
+	}
+	s.stops[script.Name] = stop
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(script.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				err := s.run(script)
+				if err != nil {
+					log.Printf("run installed script %q: %s", script.Name, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,109 @@
+package juclient
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ejuju/jus/pkg/jul"
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+func TestScheduler(t *testing.T) {
+	t.Run("persists installed scripts", func(t *testing.T) {
+		store := jul.NewMemoryStore()
+		s := NewScheduler(store, nil, func(Script) error { return nil })
+		defer s.Stop()
+		err := s.HandleControl(nil, jutp.Control{
+			Command: jutp.CommandInstall,
+			Params:  map[string]string{"name": "status", "interval": "1m"},
+			Body:    `"status" retrieve`,
+		})
+		if err != nil {
+			panic(err)
+		}
+		if s.Len() != 1 {
+			t.Fatalf("got %d scheduled scripts instead of 1", s.Len())
+		}
+
+		// A new scheduler resumes persisted scripts
+		s2 := NewScheduler(store, nil, func(Script) error { return nil })
+		defer s2.Stop()
+		err = s2.Start()
+		if err != nil {
+			panic(err)
+		}
+		if s2.Len() != 1 {
+			t.Fatalf("got %d scheduled scripts instead of 1", s2.Len())
+		}
+
+		err = s2.HandleControl(nil, jutp.Control{Command: jutp.CommandUninstall, Params: map[string]string{"name": "status"}})
+		if err != nil {
+			panic(err)
+		}
+		keys, err := store.Keys()
+		if err != nil {
+			panic(err)
+		}
+		if s2.Len() != 0 || len(keys) != 0 {
+			t.Fatalf("got %d scheduled scripts and %d stored scripts after uninstall", s2.Len(), len(keys))
+		}
+	})
+
+	t.Run("rejects short intervals", func(t *testing.T) {
+		s := NewScheduler(jul.NewMemoryStore(), nil, func(Script) error { return nil })
+		err := s.Install(Script{Name: "spam", Interval: time.Millisecond, Code: "noop"})
+		if err == nil {
+			t.Fatal("got nil error")
+		}
+	})
+	t.Run("asks the user before installing scripts", func(t *testing.T) {
+		for _, granted := range []bool{false, true} {
+			ui := &permissionUI{granted: granted}
+			s := NewScheduler(jul.NewMemoryStore(), jul.NewPermissions("example.com", nil), func(Script) error { return nil })
+			defer s.Stop()
+			err := s.HandleControl(ui, jutp.Control{
+				Command: jutp.CommandInstall,
+				Params:  map[string]string{"name": "status", "interval": "1m"},
+				Body:    `"status" retrieve`,
+			})
+			if granted != (err == nil) || granted != (s.Len() == 1) {
+				t.Fatalf("got error %v and %d scheduled scripts when granted is %v", err, s.Len(), granted)
+			}
+			if len(ui.asked) != 1 || ui.asked[0] != jul.CapabilityInstall {
+				t.Fatalf("got capabilities %q asked instead of %q", ui.asked, jul.CapabilityInstall)
+			}
+		}
+	})
+
+	t.Run("limits the number of scripts", func(t *testing.T) {
+		s := NewScheduler(jul.NewMemoryStore(), nil, func(Script) error { return nil })
+		defer s.Stop()
+		for i := 0; i <= MaxScripts; i++ {
+			err := s.Install(Script{Name: fmt.Sprint("script", i), Interval: time.Minute, Code: "noop"})
+			if i < MaxScripts && err != nil {
+				panic(err)
+			} else if i == MaxScripts && err == nil {
+				t.Fatal("got nil error")
+			}
+		}
+
+		// Scripts can still be replaced
+		err := s.Install(Script{Name: "script0", Interval: time.Hour, Code: "noop"})
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+	})
+}
+
+// permissionUI records the capabilities asked and gives the same answer to all.
+type permissionUI struct {
+	blockingUI
+	granted bool
+	asked   []jul.Capability
+}
+
+func (ui *permissionUI) AskPermission(origin string, c jul.Capability) (bool, error) {
+	ui.asked = append(ui.asked, c)
+	return ui.granted, nil
+}
@@ -0,0 +1,201 @@
+package juclient
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/ejuju/jus/pkg/jul"
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+// ErrSessionClosed is returned when the server closes the connection while the script waits for user input.
+var ErrSessionClosed = errors.New("session closed by server")
+
+// Session executes all messages received from a server on the same VM,
+// so that words defined by previous messages remain available.
+//
+// Messages received while a script is waiting for user input (with "read")
+// are executed right away, before the script resumes.
+type Session struct {
+	VM *jul.VM
+
+	ui        *sessionUI
+	onControl func(jul.UI, jutp.Control) error
+	messages  chan jutp.Message
+	readErr   error
+}
+
+// NewSession starts receiving messages from the given connection.
+// UI and VM options are used to create the session VM,
+// control messages are passed to the given function with the UI of the VM (to ask the user).
+func NewSession(conn net.Conn, ui jul.UI, onControl func(jul.UI, jutp.Control) error, opts ...jul.Option) *Session {
+	s := &Session{onControl: onControl, messages: make(chan jutp.Message)}
+	sui := &sessionUI{UI: ui, session: s}
+	s.ui = sui
+	s.VM = jul.NewVM(append(opts, jul.WithUI(sui), jul.WithServerConnection(conn))...)
+
+	go func() {
+		defer close(s.messages)
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := jutp.Read(r)
+			if err != nil {
+				s.readErr = err
+				return
+			}
+			s.messages <- msg
+		}
+	}()
+	return s
+}
+
+// Run handles messages until the server closes the connection.
+// Script errors are reported to the user and the next messages are still executed,
+// so only connection errors (and errors writing to the UI) are returned.
+func (s *Session) Run() error {
+	for msg := range s.messages {
+		err := s.handle(msg)
+		if errors.Is(err, ErrSessionClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	if errors.Is(s.readErr, io.EOF) {
+		return nil
+	}
+	return s.readErr
+}
+
+// handle executes a message, its errors are reported to the user.
+func (s *Session) handle(msg jutp.Message) error {
+	err := s.execute(msg)
+	if err == nil || errors.Is(err, ErrSessionClosed) {
+		return err
+	}
+	return s.ui.UI.Write(fmt.Sprintf("\nError: %s\n", err))
+}
+
+func (s *Session) execute(msg jutp.Message) error {
+	if !msg.IsControl() {
+		return s.VM.Execute(strings.NewReader(string(msg)))
+	}
+	c, err := jutp.ParseControl(msg)
+	if err != nil {
+		return err
+	}
+	if s.onControl == nil {
+		return fmt.Errorf("unexpected control message %q", c.Command)
+	}
+	return s.onControl(s.ui, c)
+}
+
+// sessionUI executes incoming messages while waiting for user input.
+type sessionUI struct {
+	jul.UI
+	session *Session
+	pending chan readResult // Result of the ongoing read on the underlying UI
+}
+
+type readResult struct {
+	line string
+	err  error
+}
+
+func (ui *sessionUI) Read() (string, error) { return ui.read(ui.UI.Read) }
+
+// ReadMax reads with the limit of the underlying UI, if it implements jul.BoundedReader.
+func (ui *sessionUI) ReadMax(n int) (string, error) {
+	bounded, ok := ui.UI.(jul.BoundedReader)
+	if !ok {
+		return ui.Read()
+	}
+	return ui.read(func() (string, error) { return bounded.ReadMax(n) })
+}
+
+func (ui *sessionUI) read(readLine func() (string, error)) (string, error) {
+	for {
+		// Read from the underlying UI in the background, a read that is interrupted
+		// by an incoming message is resumed by the next call.
+		// The read is started again if a message took its result (by reading as well).
+		if ui.pending == nil {
+			pending := make(chan readResult, 1)
+			go func() {
+				line, err := readLine()
+				pending <- readResult{line: line, err: err}
+			}()
+			ui.pending = pending
+		}
+
+		select {
+		case res := <-ui.pending:
+			ui.pending = nil
+			return res.line, res.err
+		case msg, ok := <-ui.session.messages:
+			if !ok {
+				return "", ErrSessionClosed
+			}
+			err := ui.session.handle(msg)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+}
+
+// WriteFormatted writes formatted messages with the underlying UI (as the embedded UI hides the method).
+func (ui *sessionUI) WriteFormatted(msg jul.Message) error { return jul.WriteFormatted(ui.UI, msg) }
+
+// ConfirmUpload asks the user through the underlying UI, unless a read is pending.
+func (ui *sessionUI) ConfirmUpload(u jul.Upload) (bool, error) {
+	if ui.pending != nil {
+		return false, errors.New("can't confirm upload while waiting for user input")
+	}
+	return ui.UI.ConfirmUpload(u)
+}
+
+// AskPermission asks the user through the underlying UI, unless a read is pending
+// (as the answer would be consumed by the pending read).
+func (ui *sessionUI) AskPermission(origin string, c jul.Capability) (bool, error) {
+	prompter, ok := ui.UI.(jul.PermissionPrompter)
+	if !ok {
+		return false, fmt.Errorf("%w: UI can't ask for permissions", jul.ErrPermissionDenied)
+	}
+	if ui.pending != nil {
+		return false, errors.New("can't ask for permission while waiting for user input")
+	}
+	return prompter.AskPermission(origin, c)
+}
+
+// richUI returns the underlying UI if it implements jul.RichUI and no read is pending.
+func (ui *sessionUI) richUI() (jul.RichUI, bool) {
+	rich, ok := ui.UI.(jul.RichUI)
+	return rich, ok && ui.pending == nil
+}
+
+// Choose uses the underlying UI if possible, otherwise the menu is answered through Read
+// (so that incoming messages are still executed).
+func (ui *sessionUI) Choose(prompt string, options []string) (int, error) {
+	if rich, ok := ui.richUI(); ok {
+		return rich.Choose(prompt, options)
+	}
+	return jul.TextUI{UI: ui}.Choose(prompt, options)
+}
+
+func (ui *sessionUI) Confirm(prompt string) (bool, error) {
+	if rich, ok := ui.richUI(); ok {
+		return rich.Confirm(prompt)
+	}
+	return jul.TextUI{UI: ui}.Confirm(prompt)
+}
+
+func (ui *sessionUI) Input(prompt string, kind jul.InputKind) (string, error) {
+	if rich, ok := ui.richUI(); ok {
+		return rich.Input(prompt, kind)
+	}
+	return jul.TextUI{UI: ui}.Input(prompt, kind)
+}
@@ -0,0 +1,182 @@
+package juclient
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ejuju/jus/pkg/jul"
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+// dialTestServer returns a client connection to a server that sends the given messages and closes the connection.
+func dialTestServer(t *testing.T, messages ...string) *net.TCPConn {
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.AcceptTCP()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for _, msg := range messages {
+			_, err = jutp.Write(conn, jutp.Message(msg))
+			if err != nil {
+				return
+			}
+		}
+	}()
+	conn, err := net.DialTCP("tcp", nil, l.Addr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// blockingUI records written messages and never returns from Read.
+type blockingUI struct{ out strings.Builder }
+
+func (ui *blockingUI) Write(msg string) error                   { ui.out.WriteString(msg); return nil }
+func (ui *blockingUI) Read() (string, error)                    { select {} }
+func (ui *blockingUI) ConfirmUpload(u jul.Upload) (bool, error) { return true, nil }
+
+// lineUI returns the lines sent on its channel and passes written messages to its other channel.
+type lineUI struct {
+	blockingUI
+	lines   chan string
+	written chan string
+}
+
+func (ui *lineUI) Write(msg string) error {
+	ui.written <- msg
+	return ui.blockingUI.Write(msg)
+}
+
+func (ui *lineUI) Read() (string, error) { return <-ui.lines, nil }
+
+// boundedUI records the limit of the last read.
+type boundedUI struct {
+	blockingUI
+	max int
+}
+
+func (ui *boundedUI) ReadMax(n int) (string, error) { ui.max = n; return "", nil }
+
+// refusingUI is a blockingUI that refuses all uploads.
+type refusingUI struct{ blockingUI }
+
+func (ui *refusingUI) ConfirmUpload(u jul.Upload) (bool, error) { return false, nil }
+
+func TestSession(t *testing.T) {
+	t.Run("keeps definitions across messages", func(t *testing.T) {
+		ui := &blockingUI{}
+		conn := dialTestServer(t, `*greet ["Hello " write] define`, `greet "world" write`)
+		err := NewSession(conn, ui, nil).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ui.out.String(); got != "Hello world" {
+			t.Fatalf("got output %q", got)
+		}
+	})
+
+	t.Run("executes messages while waiting for user input", func(t *testing.T) {
+		ui := &blockingUI{}
+		conn := dialTestServer(t, `"?> " write read`, `"Received" write`)
+		err := NewSession(conn, ui, nil).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ui.out.String(); got != "?> Received" {
+			t.Fatalf("got output %q", got)
+		}
+	})
+
+	t.Run("resumes reading after a message that reads as well", func(t *testing.T) {
+		ui := &lineUI{lines: make(chan string), written: make(chan string, 8)}
+		client, server := net.Pipe()
+		defer server.Close()
+		done := make(chan error, 1)
+		go func() { done <- NewSession(client, ui, nil).Run() }()
+
+		// The second message is executed while the first one waits for input, both read a line
+		for _, msg := range []jutp.Message{`read "1:" swap add write`, `"?" write read "2:" swap add write`} {
+			_, err := jutp.Write(server, msg)
+			if err != nil {
+				panic(err)
+			}
+		}
+		for _, step := range []struct{ line, want string }{{"", "?"}, {"a", "2:a"}, {"b", "1:b"}} {
+			if step.line != "" {
+				select {
+				case ui.lines <- step.line:
+				case <-time.After(time.Second):
+					t.Fatalf("line %q was not read", step.line)
+				}
+			}
+			select {
+			case got := <-ui.written:
+				if got != step.want {
+					t.Fatalf("got output %q instead of %q", got, step.want)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%q was not written", step.want)
+			}
+		}
+		server.Close()
+		err := <-done
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("reports script errors and executes the next messages", func(t *testing.T) {
+		ui := &refusingUI{}
+		conn := dialTestServer(t, `"secret" retrieve`, `"?> " write read`, `unknown-word`, `"still here" write`)
+		err := NewSession(conn, ui, nil).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ui.out.String()
+		if !strings.Contains(got, jul.ErrUploadDenied.Error()) || !strings.Contains(got, "unknown-word") {
+			t.Fatalf("got output %q without the errors", got)
+		}
+		if !strings.HasSuffix(got, "still here") {
+			t.Fatalf("got output %q", got)
+		}
+	})
+
+	t.Run("limits the length of lines read", func(t *testing.T) {
+		ui := &boundedUI{}
+		conn, _ := net.Pipe()
+		defer conn.Close()
+		session := NewSession(conn, ui, nil, jul.WithMaxMemory(1<<16))
+		err := session.VM.Execute(strings.NewReader("read"))
+		if err != nil {
+			panic(err)
+		}
+		if ui.max <= 0 || ui.max > 1<<16 {
+			t.Fatalf("got limit %d", ui.max)
+		}
+	})
+
+	t.Run("passes control messages to the handler", func(t *testing.T) {
+		var got []string
+		conn := dialTestServer(t, string(jutp.Control{Command: jutp.CommandUninstall}.Message()))
+		err := NewSession(conn, &blockingUI{}, func(_ jul.UI, c jutp.Control) error {
+			got = append(got, c.Command)
+			return nil
+		}, jul.WithStore(jul.NewMemoryStore())).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0] != jutp.CommandUninstall {
+			t.Fatalf("got commands %v", got)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package juclient
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"sync"
+)
+
+// TraceConn returns a connection that logs every JuTP message sent and received.
+func TraceConn(conn net.Conn, logger *log.Logger) net.Conn {
+	return &traceConn{Conn: conn, logger: logger}
+}
+
+type traceConn struct {
+	net.Conn
+	logger *log.Logger
+
+	mu       sync.Mutex
+	sent     []byte // Bytes of the message being sent
+	received []byte // Bytes of the message being received
+}
+
+func (c *traceConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.mu.Lock()
+	c.sent = c.trace("sent", c.sent, p[:n])
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *traceConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.mu.Lock()
+	c.received = c.trace("received", c.received, p[:n])
+	c.mu.Unlock()
+	return n, err
+}
+
+// trace logs each complete (null-terminated) message and returns the bytes of the incomplete one.
+func (c *traceConn) trace(direction string, msg, p []byte) []byte {
+	for {
+		i := bytes.IndexByte(p, 0)
+		if i < 0 {
+			return append(msg, p...)
+		}
+		msg = append(msg, p[:i]...)
+		c.logger.Printf("%s %s: %q", direction, c.RemoteAddr(), msg)
+		msg, p = msg[:0], p[i+1:]
+	}
+}
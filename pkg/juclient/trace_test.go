@@ -0,0 +1,43 @@
+package juclient
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"testing"
+
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+func TestTraceConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	out := &bytes.Buffer{}
+	conn := TraceConn(client, log.New(out, "", 0))
+
+	go func() {
+		// Write a message in two chunks
+		_, _ = server.Write([]byte(`"Hello" `))
+		_, _ = server.Write([]byte("write\x00"))
+	}()
+	buf := make([]byte, 64)
+	n := 0
+	for !bytes.HasSuffix(buf[:n], []byte{0}) {
+		m, err := conn.Read(buf[n:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		n += m
+	}
+
+	go func() { _, _ = server.Read(make([]byte, 64)) }()
+	_, err := jutp.Write(conn, "answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "received pipe: \"\\\"Hello\\\" write\"\nsent pipe: \"answer\"\n"
+	if got := out.String(); got != want {
+		t.Fatalf("got %q instead of %q", got, want)
+	}
+}
@@ -0,0 +1,229 @@
+package juhttp
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ejuju/jus/pkg/juclient"
+	"github.com/ejuju/jus/pkg/jul"
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+// Limits of the VMs executing scripts for chat sessions.
+const (
+	chatMaxSteps  = 10_000_000
+	chatMaxMemory = 16 << 20
+)
+
+// maxPendingAnswers is the number of answers a browser can send before the script asks for them.
+const maxPendingAnswers = 8
+
+var errTooManyAnswers = errors.New("too many answers waiting for a question")
+
+//go:embed web
+var webFiles embed.FS
+
+// serveWebUI serves the chat page and its assets.
+func serveWebUI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	root, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	http.FileServer(http.FS(root)).ServeHTTP(w, r)
+}
+
+// chatEvent is an update of the chat page, sent as JSON.
+type chatEvent struct {
+	Type    string        `json:"type"`              // "write", "read", "choose", "confirm", "input" or "error"
+	Text    string        `json:"text,omitempty"`    // Written text, prompt or error message
+	Spans   jul.Message   `json:"spans,omitempty"`   // Formatted text (for "write")
+	Options []string      `json:"options,omitempty"` // For "choose", answered with the option index
+	Kind    jul.InputKind `json:"kind,omitempty"`    // For "input"
+}
+
+// startChat executes the scripts sent by the server on the gateway, with a UI that sends events to the browser.
+// The event channel is closed when the server closes the connection or when the session is closed.
+func (s *session) startChat() error {
+	_, err := jutp.Write(s.conn, jutp.Hello(""))
+	if err != nil {
+		close(s.events)
+		return err
+	}
+
+	s.chat = &chatUI{session: s, answers: make(chan string, maxPendingAnswers)}
+	origin := s.conn.RemoteAddr().String()
+	cs := juclient.NewSession(s.conn, s.chat, s.handleControl,
+		jul.WithOrigin(origin),
+		jul.WithPermissions(jul.NewPermissions(origin, jul.NewMemoryStore())),
+		jul.WithMaxSteps(chatMaxSteps),
+		jul.WithMaxMemory(chatMaxMemory),
+	)
+	go func() {
+		defer s.chat.end()
+		err := cs.Run()
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			s.logf("run: %s", err)
+			_ = s.chat.emit(chatEvent{Type: "error", Text: err.Error()})
+		}
+	}()
+	return nil
+}
+
+// handleControl ignores scripts installed by the server, as browsers can't run them periodically.
+func (s *session) handleControl(_ jul.UI, c jutp.Control) error {
+	s.logf("ignored control message %q", c.Command)
+	return nil
+}
+
+// chatUI shows the conversation in the browser.
+type chatUI struct {
+	session *session
+	answers chan string
+
+	mu    sync.Mutex
+	ended bool // True once the event channel is closed
+}
+
+// end closes the event channel when the scripts are done.
+// A read interrupted by the end of the session may still be pending, so events are sent under the mutex.
+func (ui *chatUI) end() {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.ended = true
+	close(ui.session.events)
+}
+
+// emit sends an event to the browser.
+func (ui *chatUI) emit(ev chatEvent) error {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	if ui.ended {
+		return net.ErrClosed
+	}
+	select {
+	case ui.session.events <- ev:
+		return nil
+	case <-ui.session.done:
+		return net.ErrClosed
+	}
+}
+
+// answer passes a message of the browser to the script.
+func (ui *chatUI) answer(text string) error {
+	select {
+	case ui.answers <- text:
+		ui.session.mu.Lock()
+		ui.session.sent++
+		ui.session.mu.Unlock()
+		return nil
+	case <-ui.session.done:
+		return net.ErrClosed
+	default:
+		return errTooManyAnswers
+	}
+}
+
+// ask sends the event and waits for an answer accepted by the given function,
+// an error event is sent and the question is asked again for invalid answers.
+func (ui *chatUI) ask(ev chatEvent, accept func(answer string) error) error {
+	for {
+		err := ui.emit(ev)
+		if err != nil {
+			return err
+		}
+		var answer string
+		select {
+		case answer = <-ui.answers:
+		case <-ui.session.done:
+			return net.ErrClosed
+		}
+		err = accept(strings.TrimSpace(answer))
+		if err == nil {
+			return nil
+		}
+		err = ui.emit(chatEvent{Type: "error", Text: err.Error()})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (ui *chatUI) Write(msg string) error { return ui.emit(chatEvent{Type: "write", Text: msg}) }
+
+func (ui *chatUI) WriteFormatted(msg jul.Message) error {
+	return ui.emit(chatEvent{Type: "write", Spans: msg})
+}
+
+func (ui *chatUI) Read() (string, error) {
+	var line string
+	err := ui.ask(chatEvent{Type: "read"}, func(answer string) error {
+		line = answer
+		return nil
+	})
+	return line, err
+}
+
+func (ui *chatUI) Choose(prompt string, options []string) (int, error) {
+	if len(options) == 0 {
+		return 0, fmt.Errorf("no options to choose from")
+	}
+	var choice int
+	err := ui.ask(chatEvent{Type: "choose", Text: prompt, Options: options}, func(answer string) error {
+		n, err := strconv.Atoi(answer)
+		if err != nil || n < 0 || n >= len(options) {
+			return fmt.Errorf("invalid choice %q", answer)
+		}
+		choice = n
+		return nil
+	})
+	return choice, err
+}
+
+func (ui *chatUI) Confirm(prompt string) (bool, error) {
+	var yes bool
+	err := ui.ask(chatEvent{Type: "confirm", Text: prompt}, func(answer string) error {
+		switch answer {
+		default:
+			return fmt.Errorf("invalid answer %q", answer)
+		case "yes":
+			yes = true
+		case "no":
+			yes = false
+		}
+		return nil
+	})
+	return yes, err
+}
+
+func (ui *chatUI) Input(prompt string, kind jul.InputKind) (string, error) {
+	var value string
+	err := ui.ask(chatEvent{Type: "input", Text: prompt, Kind: kind}, func(answer string) error {
+		value = answer
+		return jul.ValidateInput(kind, answer)
+	})
+	return value, err
+}
+
+func (ui *chatUI) ConfirmUpload(u jul.Upload) (bool, error) {
+	prompt := fmt.Sprintf("Send this to %s?\n%s", u.Origin, u.Data)
+	if len(u.UserInputs) > 0 {
+		prompt += fmt.Sprintf("\nIt includes what you typed: %q", u.UserInputs)
+	}
+	return ui.Confirm(prompt)
+}
+
+func (ui *chatUI) AskPermission(origin string, c jul.Capability) (bool, error) {
+	return ui.Confirm(fmt.Sprintf("Allow %s to use %s?", origin, c.Description()))
+}
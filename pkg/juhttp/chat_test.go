@@ -0,0 +1,215 @@
+package juhttp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ejuju/jus/pkg/jul"
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+// startGreetingServer starts a JuTP server that asks for the user's name and greets them.
+func startGreetingServer(t *testing.T) *net.TCPAddr {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				hello, err := jutp.Read(r)
+				if err != nil || !hello.IsControl() {
+					return
+				}
+				_, err = jutp.Write(conn, `"What's your name? " write "Ju" write-bold read retrieve`)
+				if err != nil {
+					return
+				}
+				name, err := jutp.Read(r)
+				if err != nil {
+					return
+				}
+				code, err := jul.Format(`"Hello, " %s add write`, string(name))
+				if err != nil {
+					return
+				}
+				_, _ = jutp.Write(conn, jutp.Message(code))
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr)
+}
+
+// startClosingServer starts a JuTP server that sends the given script and closes the connection.
+func startClosingServer(t *testing.T, script jutp.Message) *net.TCPAddr {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, err := jutp.Read(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+				_, _ = jutp.Write(conn, script)
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr)
+}
+
+func TestChat(t *testing.T) {
+	gw := NewGateway(startGreetingServer(t))
+	gw.Logger = log.New(io.Discard, "", 0)
+	srv := httptest.NewServer(gw)
+	defer srv.Close()
+
+	t.Run("serves the chat page", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/")
+		if err != nil {
+			panic(err)
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			panic(err)
+		}
+		if res.StatusCode != http.StatusOK || !strings.Contains(string(b), "chat.js") {
+			t.Fatalf("got status %d and page %q", res.StatusCode, b)
+		}
+	})
+
+	t.Run("executes scripts and relays answers", func(t *testing.T) {
+		res, err := http.Post(srv.URL+"/api/sessions?ui=web", "", nil)
+		if err != nil {
+			panic(err)
+		}
+		var body struct{ ID string }
+		err = json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+		if err != nil {
+			panic(err)
+		}
+		id := body.ID
+
+		res, err = http.Get(srv.URL + "/api/sessions/" + id + "/events")
+		if err != nil {
+			panic(err)
+		}
+		defer res.Body.Close()
+		events := bufio.NewReader(res.Body)
+
+		next := func() chatEvent {
+			event, data, err := readEvent(events)
+			if err != nil {
+				panic(err)
+			}
+			if event == "close" {
+				return chatEvent{Type: "close"}
+			}
+			var ev chatEvent
+			err = json.Unmarshal([]byte(data), &ev)
+			if err != nil {
+				panic(err)
+			}
+			return ev
+		}
+		answer := func(text string) {
+			if status := postMessage(t, srv.URL, id, text); status != http.StatusNoContent {
+				t.Fatalf("got status %d instead of %d", status, http.StatusNoContent)
+			}
+		}
+
+		if ev := next(); ev.Type != "write" || ev.Text != "What's your name? " {
+			t.Fatalf("got event %+v instead of the question", ev)
+		}
+		if ev := next(); ev.Type != "write" || len(ev.Spans) != 1 || ev.Spans[0].Style != jul.StyleBold {
+			t.Fatalf("got event %+v instead of bold text", ev)
+		}
+		if ev := next(); ev.Type != "read" {
+			t.Fatalf("got event %+v instead of read", ev)
+		}
+		answer("Ju")
+
+		// Network permission, then upload confirmation
+		if ev := next(); ev.Type != "confirm" {
+			t.Fatalf("got event %+v instead of confirm", ev)
+		}
+		answer("maybe")
+		if ev := next(); ev.Type != "error" {
+			t.Fatalf("got event %+v instead of error", ev)
+		}
+		for i := 0; i < 2; i++ {
+			if ev := next(); ev.Type != "confirm" {
+				t.Fatalf("got event %+v instead of confirm", ev)
+			}
+			answer("yes")
+		}
+
+		if ev := next(); ev.Type != "write" || ev.Text != "Hello, Ju" {
+			t.Fatalf("got event %+v instead of the greeting", ev)
+		}
+		if ev := next(); ev.Type != "close" {
+			t.Fatalf("got event %+v instead of close", ev)
+		}
+	})
+}
+
+func TestChatDisconnect(t *testing.T) {
+	gw := NewGateway(startClosingServer(t, `"bye" write read`))
+	gw.Logger = log.New(io.Discard, "", 0)
+	srv := httptest.NewServer(gw)
+	defer srv.Close()
+
+	// The pending read must not send events once the server has closed the connection
+	for i := 0; i < 50; i++ {
+		res, err := http.Post(srv.URL+"/api/sessions?ui=web", "", nil)
+		if err != nil {
+			panic(err)
+		}
+		var body struct{ ID string }
+		err = json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+		if err != nil {
+			panic(err)
+		}
+
+		res, err = http.Get(srv.URL + "/api/sessions/" + body.ID + "/events")
+		if err != nil {
+			panic(err)
+		}
+		events := bufio.NewReader(res.Body)
+		for {
+			event, _, err := readEvent(events)
+			if err != nil {
+				panic(err)
+			}
+			if event == "close" {
+				break
+			}
+		}
+		res.Body.Close()
+	}
+}
@@ -1,11 +1,383 @@
 package juhttp
 
-import "net"
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+// Gateway lets browsers talk to a JuTP server over HTTP.
+//
+// Each browser session gets its own JuTP connection to the server, messages are relayed unchanged:
+//   - POST /api/sessions opens a session and returns its ID as JSON ({"id": "..."}).
+//   - GET /api/sessions/{id}/events streams messages received from the server as server-sent events,
+//     each event data is the message encoded as a JSON string. A "close" event is sent when the
+//     server closes the connection.
+//   - POST /api/sessions/{id}/messages sends the request body to the server as a single message.
+//   - DELETE /api/sessions/{id} closes the session.
+//
+// POST /api/sessions?ui=web opens a chat session instead: scripts sent by the server are executed
+// on the gateway and events are UI updates for the chat page served on all other paths (see chatEvent),
+// messages are the user's answers.
+// A different page can be served with the UI field (like the host page of the WebAssembly build).
+//
+// Sessions without an event stream are closed after IdleTimeout.
 type Gateway struct {
 	forwardTo *net.TCPAddr
+
+	Logger         *log.Logger
+	IdleTimeout    time.Duration // Defaults to 5 minutes
+	MaxMessageSize int64         // Maximum size of messages sent by browsers, defaults to 1 MiB
+	UI             http.Handler  // Serves all paths outside of /api/, defaults to the chat page
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	srv      *http.Server
+	closing  chan struct{} // Closed on shutdown
+	closed   bool
+}
+
+// NewGateway returns a gateway that relays messages to the JuTP server at the given address.
+func NewGateway(forwardTo *net.TCPAddr) *Gateway {
+	return &Gateway{
+		forwardTo:      forwardTo,
+		Logger:         log.Default(),
+		IdleTimeout:    5 * time.Minute,
+		MaxMessageSize: 1 << 20,
+		sessions:       map[string]*session{},
+		closing:        make(chan struct{}),
+	}
+}
+
+// Run serves HTTP on the given port until Shutdown is called.
+func (gw *Gateway) Run(port int) error {
+	gw.mu.Lock()
+	gw.srv = &http.Server{Addr: ":" + strconv.Itoa(port), Handler: gw, ReadHeaderTimeout: 10 * time.Second}
+	srv := gw.srv
+	gw.mu.Unlock()
+	gw.Logger.Printf("gateway listening on %s, forwarding to %s", srv.Addr, gw.forwardTo)
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown closes all sessions and stops the HTTP server (if started with Run),
+// waiting for ongoing requests to complete until the context is done.
+func (gw *Gateway) Shutdown(ctx context.Context) error {
+	gw.mu.Lock()
+	if !gw.closed {
+		gw.closed = true
+		close(gw.closing)
+	}
+	sessions := make([]*session, 0, len(gw.sessions))
+	for _, s := range gw.sessions {
+		sessions = append(sessions, s)
+	}
+	srv := gw.srv
+	gw.mu.Unlock()
+
+	for _, s := range sessions {
+		s.close("gateway shutdown")
+	}
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
+func (gw *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		if gw.UI != nil {
+			gw.UI.ServeHTTP(w, r)
+			return
+		}
+		serveWebUI(w, r)
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/sessions"), "/")
+	if !strings.HasPrefix(r.URL.Path, "/api/sessions") {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Create session
+	if path == "" {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		gw.handleCreate(w, r)
+		return
+	}
+
+	// Handle existing session
+	id, action, _ := strings.Cut(path, "/")
+	gw.mu.Lock()
+	s, ok := gw.sessions[id]
+	gw.mu.Unlock()
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	switch {
+	default:
+		http.NotFound(w, r)
+	case action == "" && r.Method == http.MethodDelete:
+		s.close("closed by browser")
+		w.WriteHeader(http.StatusNoContent)
+	case action == "events" && r.Method == http.MethodGet:
+		gw.handleEvents(w, r, s)
+	case action == "messages" && r.Method == http.MethodPost:
+		gw.handleMessage(w, r, s)
+	}
+}
+
+func (gw *Gateway) handleCreate(w http.ResponseWriter, r *http.Request) {
+	conn, err := net.DialTimeout("tcp", gw.forwardTo.String(), 10*time.Second)
+	if err != nil {
+		gw.Logger.Printf("dial %s: %s", gw.forwardTo, err)
+		http.Error(w, "server unavailable", http.StatusBadGateway)
+		return
+	}
+
+	s := &session{id: newSessionID(), gw: gw, conn: conn, events: make(chan any, 64), done: make(chan struct{})}
+	gw.mu.Lock()
+	if gw.closed {
+		gw.mu.Unlock()
+		conn.Close()
+		http.Error(w, "gateway is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	gw.sessions[s.id] = s
+	gw.mu.Unlock()
+	s.mu.Lock()
+	s.idle = time.AfterFunc(gw.IdleTimeout, func() { s.close("idle timeout") })
+	s.mu.Unlock()
+	if r.URL.Query().Get("ui") == "web" {
+		err = s.startChat()
+		if err != nil {
+			s.logf("start chat: %s", err)
+			s.close("failed to start")
+			http.Error(w, "server unavailable", http.StatusBadGateway)
+			return
+		}
+		s.logf("opened chat for %s", r.RemoteAddr)
+	} else {
+		go s.receive()
+		s.logf("opened for %s", r.RemoteAddr)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": s.id})
+}
+
+func (gw *Gateway) handleEvents(w http.ResponseWriter, r *http.Request, s *session) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	if !s.attach() {
+		http.Error(w, "session already has an event stream", http.StatusConflict)
+		return
+	}
+	defer s.detach()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Keep the connection alive through proxies
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-gw.closing:
+			return
+		case <-ping.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-s.events:
+			if !ok {
+				_, _ = io.WriteString(w, "event: close\ndata: {}\n\n")
+				flusher.Flush()
+				s.close("closed by server")
+				return
+			}
+			b, err := json.Marshal(ev)
+			if err != nil {
+				return
+			}
+			_, err = fmt.Fprintf(w, "data: %s\n\n", b)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (gw *Gateway) handleMessage(w http.ResponseWriter, r *http.Request, s *session) {
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, gw.MaxMessageSize))
+	if err != nil {
+		http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if strings.IndexByte(string(b), 0) >= 0 {
+		http.Error(w, "message contains a null character", http.StatusBadRequest)
+		return
+	}
+	if s.chat != nil {
+		err = s.chat.answer(string(b))
+		if errors.Is(err, errTooManyAnswers) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+	} else {
+		err = s.send(jutp.Message(b))
+	}
+	if err != nil {
+		s.logf("send: %s", err)
+		http.Error(w, "failed to send message", http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// session is a browser session relayed to its own JuTP connection.
+type session struct {
+	id     string
+	gw     *Gateway
+	conn   net.Conn
+	events chan any      // Events for the browser (closed when the connection is closed or the chat ends)
+	done   chan struct{} // Closed when the session is closed
+	chat   *chatUI       // Executes scripts for chat sessions, nil for relayed sessions
+
+	mu       sync.Mutex
+	attached bool        // True while an event stream is open
+	idle     *time.Timer // Closes the session when no event stream is open for too long
+	closed   bool
+	sent     int
+	received int
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func (s *session) logf(format string, args ...any) {
+	s.gw.Logger.Printf("session %s: "+format, append([]any{s.id}, args...)...)
+}
+
+// receive reads messages from the server until the connection is closed,
+// they are sent to the browser as JSON strings.
+func (s *session) receive() {
+	defer close(s.events)
+	r := bufio.NewReader(s.conn)
+	for {
+		msg, err := jutp.Read(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logf("receive: %s", err)
+			}
+			return
+		}
+		s.mu.Lock()
+		s.received++
+		s.mu.Unlock()
+		s.events <- string(msg)
+	}
+}
+
+func (s *session) send(msg jutp.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return net.ErrClosed
+	}
+	err := s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err != nil {
+		return err
+	}
+	_, err = jutp.Write(s.conn, msg)
+	if err != nil {
+		return err
+	}
+	s.sent++
+	return nil
 }
 
-func (gw *Gateway) Run(port int) {
+// attach returns false if an event stream is already open.
+func (s *session) attach() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attached || s.closed {
+		return false
+	}
+	s.attached = true
+	s.idle.Stop()
+	return true
+}
+
+func (s *session) detach() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attached = false
+	if !s.closed {
+		s.idle.Reset(s.gw.IdleTimeout)
+	}
+}
+
+func (s *session) close(reason string) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.idle.Stop()
+	sent, received := s.sent, s.received
+	s.mu.Unlock()
+
+	s.gw.mu.Lock()
+	delete(s.gw.sessions, s.id)
+	s.gw.mu.Unlock()
+	s.conn.Close()
 
+	// Unblock the receiving goroutine if no event stream reads the remaining events
+	go func() {
+		for range s.events {
+		}
+	}()
+	s.logf("closed (%s), %d message(s) sent and %d received", reason, sent, received)
 }
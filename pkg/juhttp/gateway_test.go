@@ -0,0 +1,198 @@
+package juhttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+// startEchoServer starts a JuTP server that sends back each message it receives,
+// the connection is closed when it receives "bye".
+func startEchoServer(t *testing.T) *net.TCPAddr {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					msg, err := jutp.Read(r)
+					if err != nil || msg == "bye" {
+						return
+					}
+					_, _ = jutp.Write(conn, "echo: "+msg)
+				}
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr)
+}
+
+func newTestGateway(t *testing.T) (*Gateway, *httptest.Server) {
+	gw := NewGateway(startEchoServer(t))
+	gw.Logger = log.New(io.Discard, "", 0)
+	srv := httptest.NewServer(gw)
+	t.Cleanup(srv.Close)
+	return gw, srv
+}
+
+func createSession(t *testing.T, url string) string {
+	res, err := http.Post(url+"/api/sessions", "", nil)
+	if err != nil {
+		panic(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("got status %d instead of %d", res.StatusCode, http.StatusCreated)
+	}
+	var body struct{ ID string }
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		panic(err)
+	}
+	return body.ID
+}
+
+func postMessage(t *testing.T, url, id, msg string) int {
+	res, err := http.Post(url+"/api/sessions/"+id+"/messages", "text/plain", strings.NewReader(msg))
+	if err != nil {
+		panic(err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+// readEvent returns the next server-sent event.
+func readEvent(r *bufio.Reader) (event, data string, err error) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", "", err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && data != "":
+			return event, data, nil
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestGateway(t *testing.T) {
+	t.Run("relays messages in both directions", func(t *testing.T) {
+		_, srv := newTestGateway(t)
+		id := createSession(t, srv.URL)
+
+		res, err := http.Get(srv.URL + "/api/sessions/" + id + "/events")
+		if err != nil {
+			panic(err)
+		}
+		defer res.Body.Close()
+		events := bufio.NewReader(res.Body)
+
+		for _, msg := range []string{"hello", "multi\nline \"message\""} {
+			if status := postMessage(t, srv.URL, id, msg); status != http.StatusNoContent {
+				t.Fatalf("got status %d instead of %d", status, http.StatusNoContent)
+			}
+			_, data, err := readEvent(events)
+			if err != nil {
+				panic(err)
+			}
+			var got string
+			err = json.Unmarshal([]byte(data), &got)
+			if err != nil {
+				panic(err)
+			}
+			if got != "echo: "+msg {
+				t.Fatalf("got message %q instead of %q", got, "echo: "+msg)
+			}
+		}
+
+		// Server closes the connection
+		postMessage(t, srv.URL, id, "bye")
+		event, _, err := readEvent(events)
+		if err != nil {
+			panic(err)
+		}
+		if event != "close" {
+			t.Fatalf("got event %q instead of %q", event, "close")
+		}
+		if status := postMessage(t, srv.URL, id, "hello"); status != http.StatusNotFound {
+			t.Fatalf("got status %d instead of %d", status, http.StatusNotFound)
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		_, srv := newTestGateway(t)
+		id := createSession(t, srv.URL)
+		if status := postMessage(t, srv.URL, "unknown", "hello"); status != http.StatusNotFound {
+			t.Fatalf("got status %d instead of %d", status, http.StatusNotFound)
+		}
+		if status := postMessage(t, srv.URL, id, "a\x00b"); status != http.StatusBadRequest {
+			t.Fatalf("got status %d instead of %d", status, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("closes idle sessions", func(t *testing.T) {
+		gw, srv := newTestGateway(t)
+		gw.IdleTimeout = 20 * time.Millisecond
+		id := createSession(t, srv.URL)
+		time.Sleep(100 * time.Millisecond)
+		if status := postMessage(t, srv.URL, id, "hello"); status != http.StatusNotFound {
+			t.Fatalf("got status %d instead of %d", status, http.StatusNotFound)
+		}
+	})
+
+	t.Run("closes sessions on shutdown", func(t *testing.T) {
+		gw, srv := newTestGateway(t)
+		id := createSession(t, srv.URL)
+		res, err := http.Get(srv.URL + "/api/sessions/" + id + "/events")
+		if err != nil {
+			panic(err)
+		}
+		defer res.Body.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		err = gw.Shutdown(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status := postMessage(t, srv.URL, id, "hello"); status != http.StatusNotFound {
+			t.Fatalf("got status %d instead of %d", status, http.StatusNotFound)
+		}
+		res, err = http.Post(srv.URL+"/api/sessions", "", nil)
+		if err != nil {
+			panic(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("got status %d instead of %d", res.StatusCode, http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+// Chat page of the gateway: scripts sent by the server are executed by the gateway,
+// this page shows what they write and sends back the user's answers.
+"use strict";
+
+const messages = document.getElementById("messages");
+const statusText = document.getElementById("status");
+const form = document.getElementById("answer");
+
+let sessionURL = null;
+let bubble = null; // Bot message that written text is appended to
+
+start();
+
+async function start() {
+    const res = await fetch("api/sessions?ui=web", { method: "POST" });
+    if (!res.ok) {
+        setStatus("Unable to connect: " + (await res.text()));
+        return;
+    }
+    const { id } = await res.json();
+    sessionURL = "api/sessions/" + encodeURIComponent(id);
+
+    const events = new EventSource(sessionURL + "/events");
+    events.onopen = () => setStatus("");
+    events.onmessage = (e) => handle(JSON.parse(e.data));
+    events.addEventListener("close", () => {
+        events.close();
+        form.hidden = true;
+        setStatus("The conversation has ended.");
+    });
+    events.onerror = () => {
+        if (events.readyState === EventSource.CLOSED) {
+            form.hidden = true;
+            setStatus("The connection was lost.");
+        }
+    };
+    window.addEventListener("pagehide", () => fetch(sessionURL, { method: "DELETE", keepalive: true }));
+}
+
+function handle(ev) {
+    switch (ev.type) {
+        case "write":
+            write(ev);
+            break;
+        case "read":
+            askText("text");
+            break;
+        case "input":
+            writePrompt(ev.text);
+            askText(ev.kind);
+            break;
+        case "choose":
+            writePrompt(ev.text);
+            askButtons(ev.options.map((option, i) => [option, String(i)]));
+            break;
+        case "confirm":
+            writePrompt(ev.text);
+            askButtons([["Yes", "yes"], ["No", "no"]]);
+            break;
+        case "error":
+            addMessage("error").textContent = ev.text;
+            bubble = null;
+            break;
+    }
+}
+
+function setStatus(text) {
+    statusText.textContent = text;
+    statusText.hidden = text === "";
+}
+
+function addMessage(from) {
+    const li = document.createElement("li");
+    li.className = "message " + from;
+    messages.appendChild(li);
+    li.scrollIntoView({ block: "end" });
+    return li;
+}
+
+function write(ev) {
+    if (!bubble) {
+        bubble = addMessage("bot");
+    }
+    if (ev.spans) {
+        ev.spans.forEach((span) => bubble.appendChild(renderSpan(span)));
+    } else {
+        bubble.appendChild(document.createTextNode(ev.text));
+    }
+    bubble.scrollIntoView({ block: "end" });
+}
+
+function writePrompt(text) {
+    if (text) {
+        write({ text: text });
+    }
+}
+
+// renderSpan returns the element of a formatted text,
+// texts are never parsed as HTML and only http, https and mailto links are rendered.
+function renderSpan(span) {
+    const tags = { bold: "strong", italic: "em", code: "code", "list-item": "span" };
+    if (span.style === "link" && isSafeURL(span.url)) {
+        const a = document.createElement("a");
+        a.href = span.url;
+        a.rel = "noopener noreferrer";
+        a.target = "_blank";
+        a.textContent = span.text || span.url;
+        return a;
+    }
+    if (!tags[span.style]) {
+        return document.createTextNode(span.text);
+    }
+    const el = document.createElement(tags[span.style]);
+    if (span.style === "list-item") {
+        el.className = "item";
+    }
+    el.textContent = span.text;
+    return el;
+}
+
+function isSafeURL(url) {
+    try {
+        return ["http:", "https:", "mailto:"].includes(new URL(url).protocol);
+    } catch {
+        return false;
+    }
+}
+
+// askText shows a text input, with the type matching the input kind (text, number, date or email).
+function askText(kind) {
+    const input = document.createElement("input");
+    input.type = kind;
+    input.step = "any";
+    input.required = kind !== "text";
+    const button = document.createElement("button");
+    button.textContent = "Send";
+    form.replaceChildren(input, button);
+    form.onsubmit = (e) => {
+        e.preventDefault();
+        send(input.value, input.value);
+    };
+    form.hidden = false;
+    input.focus();
+}
+
+// askButtons shows a button for each [label, answer] pair.
+function askButtons(choices) {
+    form.replaceChildren(...choices.map(([label, answer]) => {
+        const button = document.createElement("button");
+        button.type = "button";
+        button.textContent = label;
+        button.onclick = () => send(label, answer);
+        return button;
+    }));
+    form.onsubmit = (e) => e.preventDefault();
+    form.hidden = false;
+    form.querySelector("button").focus();
+}
+
+async function send(label, answer) {
+    form.hidden = true;
+    addMessage("user").textContent = label;
+    bubble = null;
+    const res = await fetch(sessionURL + "/messages", { method: "POST", body: answer });
+    if (!res.ok) {
+        addMessage("error").textContent = "Your answer could not be sent: " + (await res.text());
+    }
+}
@@ -0,0 +1,180 @@
+package jul
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Instruction is a single compiled operation executed by the VM.
+type Instruction struct {
+	Op       Opcode
+	Cell     any           // Cell pushed on the stack (for OpPush)
+	Name     string        // Name of the called word (for OpCall)
+	Word     *Definition   // Resolved definition (for OpCall), nil until resolved
+	Code     []Instruction // Body of the literal (for OpList and OpMap)
+	Position Position
+}
+
+type Opcode uint8
+
+const (
+	OpPush Opcode = iota // Push a literal cell on the stack
+	OpCall               // Call a word from the dictionary
+	OpList               // Execute the body and push the resulting cells as a list
+	OpMap                // Execute the body and push the resulting key-value pairs as a map
+)
+
+func (op Opcode) String() string {
+	switch op {
+	case OpPush:
+		return "push"
+	case OpCall:
+		return "call"
+	case OpList:
+		return "list"
+	case OpMap:
+		return "map"
+	}
+	return "op(" + strconv.Itoa(int(op)) + ")"
+}
+
+// Compile parses the code from the given reader and compiles it to a list of instructions.
+//
+// Calls to words that already exist in the VM's dictionary are resolved at compile time,
+// other calls are resolved the first time they are executed (as words may be defined later on).
+func (vm *VM) Compile(r io.Reader) ([]Instruction, error) {
+	nodes, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return vm.compileNodes(nodes), nil
+}
+
+func (vm *VM) compileNodes(nodes []*Node) []Instruction {
+	code := make([]Instruction, 0, len(nodes))
+	for _, n := range nodes {
+		if ins, ok := vm.compileNode(n); ok {
+			code = append(code, ins)
+		}
+	}
+	return code
+}
+
+// compileNode compiles a single node, it returns false if the node produces no instruction.
+func (vm *VM) compileNode(n *Node) (Instruction, bool) {
+	switch n.Type {
+	default:
+		panic(fmt.Errorf("unreachable: unhandled token type %q", n.Type))
+	case TokenTypeEOF, TokenTypeComment:
+		return Instruction{}, false
+	case TokenTypeFunctionCall:
+		w := vm.dictionary.FindLatestDefinition(n.Value)
+		if w == nil {
+			if num, err := strconv.Atoi(n.Value); err == nil {
+				return Instruction{Op: OpPush, Cell: CellInteger(num), Position: n.Position}, true
+			}
+		}
+		return Instruction{Op: OpCall, Name: n.Value, Word: w, Position: n.Position}, true
+	case TokenTypeQuotation:
+		quotation := CellQuotation{Source: n.Value, Code: vm.compileNodes(n.Children)}
+		return Instruction{Op: OpPush, Cell: quotation, Position: n.Position}, true
+	case TokenTypeList:
+		return Instruction{Op: OpList, Code: vm.compileNodes(n.Children), Position: n.Position}, true
+	case TokenTypeMap:
+		return Instruction{Op: OpMap, Code: vm.compileNodes(n.Children), Position: n.Position}, true
+	case TokenTypeLiteralText, TokenTypeLiteralTextWord:
+		return Instruction{Op: OpPush, Cell: CellText(n.Value), Position: n.Position}, true
+	}
+}
+
+// run executes the given instructions.
+//
+// Words that could not be resolved at compile time are looked up and cached in the instruction.
+// This is safe because words can't be redefined once they exist in the dictionary.
+func (vm *VM) run(code []Instruction) error {
+	for i := range code {
+		ins := &code[i]
+		err := vm.step(i == 0)
+		if err != nil {
+			return vm.newRuntimeError(ins, err)
+		}
+		switch ins.Op {
+		default:
+			panic(fmt.Errorf("unreachable: unhandled opcode %s", ins.Op))
+		case OpPush:
+			err := vm.stack.push(ins.Cell, nil)
+			if err != nil {
+				return vm.newRuntimeError(ins, err)
+			}
+		case OpList, OpMap:
+			depth := vm.stack.Len()
+			frame := vm.stack.enter()
+			err := vm.run(ins.Code)
+			if err != nil {
+				vm.stack.leave(frame)
+				return err
+			}
+			var c any
+			c, err = vm.stack.Collect(depth)
+			if err == nil && ins.Op == OpMap {
+				c, err = newMapFromPairs(c.(CellList))
+			}
+			if err == nil {
+				err = vm.stack.Push(c)
+			}
+			vm.stack.leave(frame)
+			if err != nil {
+				return vm.newRuntimeError(ins, err)
+			}
+		case OpCall:
+			w := ins.Word
+			if w == nil {
+				w = vm.dictionary.FindLatestDefinition(ins.Name)
+				if w == nil {
+					return vm.newRuntimeError(ins, fmt.Errorf("unknown word %q", ins.Name))
+				}
+				ins.Word = w
+			}
+			if vm.maxCallDepth > 0 && len(vm.calls) >= vm.maxCallDepth {
+				return vm.newRuntimeError(ins, ErrCallDepthLimit)
+			}
+			if w.Capability != "" && vm.permissions != nil {
+				err := vm.permissions.Check(vm.ui, w.Capability)
+				if err != nil {
+					return vm.newRuntimeError(ins, fmt.Errorf("%s: %w", w.Name, err))
+				}
+			}
+			vm.calls = append(vm.calls, Frame{Name: w.Name, Position: ins.Position})
+			frame := vm.stack.enter()
+			err := w.Func(vm)
+			vm.stack.leave(frame)
+			vm.calls = vm.calls[:len(vm.calls)-1]
+			if err != nil {
+				// Errors raised by nested instructions already hold the full call stack
+				var rerr RuntimeError
+				if errors.As(err, &rerr) {
+					return rerr
+				}
+				return vm.newRuntimeError(ins, fmt.Errorf("%s: %w", w.Name, err))
+			}
+		}
+	}
+	return nil
+}
+
+// newRuntimeError returns an error raised by the given instruction,
+// the call stack is captured from the words currently being executed.
+func (vm *VM) newRuntimeError(ins *Instruction, cause error) RuntimeError {
+	name := ins.Name
+	if ins.Op != OpCall {
+		name = ins.Op.String()
+	}
+	stack := make([]Frame, 0, len(vm.calls)+1)
+	stack = append(stack, Frame{Name: name, Position: ins.Position})
+	for i := len(vm.calls) - 1; i >= 0; i-- {
+		stack = append(stack, vm.calls[i])
+	}
+	return RuntimeError{Position: ins.Position, Cause: cause, Stack: stack}
+}
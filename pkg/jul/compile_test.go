@@ -0,0 +1,100 @@
+package jul
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+const benchmarkFizzbuzz = `
+*is-divisible-by-5       [5 is-modulo] define
+*is-divisible-by-3       [3 is-modulo] define
+*is-divisible-by-5-and-3 [dup is-divisible-by-5 over is-divisible-by-3 and] define
+
+[
+1 add
+    dup is-divisible-by-5-and-3
+    [drop "Fizzbuzz " write]
+    [
+        dup is-divisible-by-3
+        [drop "Fizz " write]
+        [
+            dup is-divisible-by-5
+            [drop "Buzz " write]
+            [to-text " " add write]
+            if
+        ]
+        if
+    ]
+    if
+1000 is-smaller
+]
+repeat
+`
+
+func TestCompile(t *testing.T) {
+	t.Run("resolves known words at compile time", func(t *testing.T) {
+		vm := NewVM()
+		code, err := vm.Compile(strings.NewReader("1 dup unknown-word"))
+		if err != nil {
+			panic(err)
+		}
+		if len(code) != 3 {
+			t.Fatalf("got %d instructions instead of %d", len(code), 3)
+		}
+		if code[0].Op != OpPush || code[0].Cell != CellInteger(1) {
+			t.Fatalf("got %+v instead of integer push", code[0])
+		}
+		if code[1].Op != OpCall || code[1].Word == nil || code[1].Word.Name != "dup" {
+			t.Fatalf("got %+v instead of resolved call to dup", code[1])
+		}
+		if code[2].Op != OpCall || code[2].Word != nil {
+			t.Fatalf("got %+v instead of unresolved call", code[2])
+		}
+	})
+
+	t.Run("compiles nested quotations", func(t *testing.T) {
+		vm := NewVM()
+		code, err := vm.Compile(strings.NewReader("[1 [2] do]"))
+		if err != nil {
+			panic(err)
+		}
+		quotation, ok := code[0].Cell.(CellQuotation)
+		if !ok {
+			t.Fatalf("got type %T", code[0].Cell)
+		}
+		if len(quotation.Code) != 3 {
+			t.Fatalf("got %d instructions instead of %d", len(quotation.Code), 3)
+		}
+		if _, ok := quotation.Code[1].Cell.(CellQuotation); !ok {
+			t.Fatalf("got type %T", quotation.Code[1].Cell)
+		}
+	})
+
+	t.Run("resolves words defined after compilation", func(t *testing.T) {
+		vm := NewVM()
+		err := vm.Execute(strings.NewReader("*call-later [later] define *later [42] define call-later"))
+		if err != nil {
+			panic(err)
+		}
+		c, err := vm.stack.Pop()
+		if err != nil {
+			panic(err)
+		}
+		if c != CellInteger(42) {
+			t.Fatalf("got %v instead of %d", c, 42)
+		}
+	})
+}
+
+func BenchmarkExecute(b *testing.B) {
+	b.Run("fizzbuzz", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			vm := NewVM(WithUI(NewDefaultUI(nil, io.Discard)))
+			err := vm.Execute(strings.NewReader(benchmarkFizzbuzz))
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
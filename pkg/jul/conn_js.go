@@ -0,0 +1,120 @@
+//go:build js && wasm
+
+package jul
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"syscall/js"
+	"time"
+)
+
+// JSConn is a connection to a server through a transport implemented in JavaScript,
+// as browsers can't open TCP connections (the transport may use the HTTP gateway for example).
+// It can be used with WithServerConnection so that "retrieve" sends messages with the transport.
+//
+// The transport object must implement send(message), which may return a promise.
+// Messages received from the server are passed to Receive.
+type JSConn struct {
+	transport js.Value
+	origin    string
+
+	wmu sync.Mutex
+	out []byte // Written bytes of an unfinished message
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	in     bytes.Buffer // Received messages (NUL-terminated) not yet read
+	closed bool
+}
+
+// NewJSConn returns a connection using the given JavaScript transport, origin identifies the server.
+func NewJSConn(transport js.Value, origin string) *JSConn {
+	c := &JSConn{transport: transport, origin: origin}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Receive passes a message received from the server to the reader of the connection.
+// It doesn't block so that it can be called from the JavaScript event loop.
+func (c *JSConn) Receive(msg string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.in.WriteString(msg)
+	c.in.WriteByte(0)
+	c.cond.Broadcast()
+}
+
+// Read returns received messages, io.EOF is returned once the connection is closed.
+func (c *JSConn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.in.Len() == 0 && !c.closed {
+		c.cond.Wait()
+	}
+	if c.in.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.in.Read(p)
+}
+
+// Write sends each complete (NUL-terminated) message with the transport.
+func (c *JSConn) Write(p []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return 0, net.ErrClosed
+	}
+
+	c.out = append(c.out, p...)
+	for {
+		i := bytes.IndexByte(c.out, 0)
+		if i < 0 {
+			return len(p), nil
+		}
+		msg := string(c.out[:i])
+		c.out = c.out[i+1:]
+		_, err := awaitJS(c.transport.Call("send", msg))
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Close stops reading messages and calls the close method of the transport (if any).
+func (c *JSConn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.cond.Broadcast()
+	c.mu.Unlock()
+	if c.transport.Get("close").Type() == js.TypeFunction {
+		c.transport.Call("close")
+	}
+	return nil
+}
+
+func (c *JSConn) LocalAddr() net.Addr  { return jsAddr("browser") }
+func (c *JSConn) RemoteAddr() net.Addr { return jsAddr(c.origin) }
+
+// Deadlines are not supported, the transport is responsible for timeouts.
+func (c *JSConn) SetDeadline(t time.Time) error      { return nil }
+func (c *JSConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *JSConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// jsAddr is the address of a JSConn.
+type jsAddr string
+
+func (a jsAddr) Network() string { return "js" }
+func (a jsAddr) String() string  { return string(a) }
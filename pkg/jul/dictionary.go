@@ -4,10 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
-
-	"github.com/ejuju/jus/pkg/jutp"
 )
 
 type Dictionary struct{ words []*Definition }
@@ -25,6 +22,15 @@ func (d *Dictionary) FindLatestDefinition(name string) *Definition {
 	return nil
 }
 
+// Words returns the names of all defined words, in definition order.
+func (d *Dictionary) Words() []string {
+	names := make([]string, len(d.words))
+	for i, w := range d.words {
+		names[i] = w.Name
+	}
+	return names
+}
+
 func (d *Dictionary) Define(w *Definition) error {
 	if w := d.FindLatestDefinition(w.Name); w != nil {
 		return fmt.Errorf("already defined word: %q", w.Name)
@@ -34,8 +40,9 @@ func (d *Dictionary) Define(w *Definition) error {
 }
 
 type Definition struct {
-	Name string
-	Func func(vm *VM) error
+	Name       string
+	Func       func(vm *VM) error
+	Capability Capability // Capability needed to call the word (empty if none)
 }
 
 var Builtins = []*Definition{
@@ -54,6 +61,18 @@ var Builtins = []*Definition{
 	{Name: "to-integer", Func: func(vm *VM) error { return vm.stack.ToInteger() }},
 	{Name: "to-text", Func: func(vm *VM) error { return vm.stack.ToText() }},
 	{Name: "invert", Func: func(vm *VM) error { return vm.stack.Invert() }},
+	{Name: "length", Func: func(vm *VM) error { return vm.stack.Length() }},
+	{Name: "get", Func: func(vm *VM) error { return vm.stack.Get() }},
+	{Name: "append", Func: func(vm *VM) error { return vm.stack.Append() }},
+	{Name: "slice", Func: func(vm *VM) error { return vm.stack.Slice() }},
+	{Name: "sort", Func: func(vm *VM) error { return vm.stack.Sort() }},
+	{Name: "map-get", Func: func(vm *VM) error { return vm.stack.MapGet() }},
+	{Name: "map-set", Func: func(vm *VM) error { return vm.stack.MapSet() }},
+	{Name: "map-has", Func: func(vm *VM) error { return vm.stack.MapHas() }},
+	{Name: "map-keys", Func: func(vm *VM) error { return vm.stack.MapKeys() }},
+	{Name: "map-delete", Func: func(vm *VM) error { return vm.stack.MapDelete() }},
+	{Name: "to-json", Func: func(vm *VM) error { return vm.stack.ToJSON() }},
+	{Name: "from-json", Func: func(vm *VM) error { return vm.stack.FromJSON() }},
 	{
 		Name: "do",
 		Func: func(vm *VM) error {
@@ -65,7 +84,7 @@ var Builtins = []*Definition{
 			if !ok {
 				return fmt.Errorf("got (A) %T instead of quotation", cellA)
 			}
-			return vm.Execute(strings.NewReader(string(quotation)))
+			return vm.run(quotation.Code)
 		},
 	},
 	{
@@ -95,7 +114,7 @@ var Builtins = []*Definition{
 			return vm.dictionary.Define(&Definition{
 				Name: string(keyword),
 				Func: func(vm *VM) error {
-					return vm.Execute(strings.NewReader(string(quotation)))
+					return vm.run(quotation.Code)
 				},
 			})
 		},
@@ -135,9 +154,9 @@ var Builtins = []*Definition{
 
 			// Execute callback depending on boolean
 			if boolean {
-				return vm.Execute(strings.NewReader(string(callbackIfTrue)))
+				return vm.run(callbackIfTrue.Code)
 			} else {
-				return vm.Execute(strings.NewReader(string(callbackIfFalse)))
+				return vm.run(callbackIfFalse.Code)
 			}
 		},
 	},
@@ -163,7 +182,7 @@ var Builtins = []*Definition{
 				}
 
 				// Execute callback
-				err = vm.Execute(strings.NewReader(string(callback)))
+				err = vm.run(callback.Code)
 				if err != nil {
 					return fmt.Errorf("executing callback (%d): %w", i, err)
 				}
@@ -184,6 +203,182 @@ var Builtins = []*Definition{
 			return nil
 		},
 	},
+	{
+		Name: "each",
+		Func: func(vm *VM) error {
+			// Pop callback
+			cellB, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (B) callback (quotation): %w", err)
+			}
+			callback, ok := cellB.(CellQuotation)
+			if !ok {
+				return fmt.Errorf("got (B) %T instead of quotation", cellB)
+			}
+
+			// Pop list
+			cellA, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (A) list: %w", err)
+			}
+			list, ok := cellA.(CellList)
+			if !ok {
+				return fmt.Errorf("got (A) %T instead of list", cellA)
+			}
+
+			// Execute callback for each item
+			for _, item := range list {
+				err = vm.stack.Push(item)
+				if err != nil {
+					return err
+				}
+				err = vm.run(callback.Code)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Name: "map",
+		Func: func(vm *VM) error {
+			// Pop callback
+			cellB, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (B) callback (quotation): %w", err)
+			}
+			callback, ok := cellB.(CellQuotation)
+			if !ok {
+				return fmt.Errorf("got (B) %T instead of quotation", cellB)
+			}
+
+			// Pop list
+			cellA, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (A) list: %w", err)
+			}
+			list, ok := cellA.(CellList)
+			if !ok {
+				return fmt.Errorf("got (A) %T instead of list", cellA)
+			}
+
+			// Replace each item by the cell returned by the callback
+			out := make(CellList, len(list))
+			for i, item := range list {
+				err = vm.stack.Push(item)
+				if err != nil {
+					return err
+				}
+				err = vm.run(callback.Code)
+				if err != nil {
+					return err
+				}
+				out[i], err = vm.stack.Pop()
+				if err != nil {
+					return fmt.Errorf("pop mapped item (%d): %w", i, err)
+				}
+			}
+			return vm.stack.Push(out)
+		},
+	},
+	{
+		Name: "filter",
+		Func: func(vm *VM) error {
+			// Pop callback
+			cellB, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (B) callback (quotation): %w", err)
+			}
+			callback, ok := cellB.(CellQuotation)
+			if !ok {
+				return fmt.Errorf("got (B) %T instead of quotation", cellB)
+			}
+
+			// Pop list
+			cellA, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (A) list: %w", err)
+			}
+			list, ok := cellA.(CellList)
+			if !ok {
+				return fmt.Errorf("got (A) %T instead of list", cellA)
+			}
+
+			// Keep items for which the callback returns true
+			out := CellList{}
+			for i, item := range list {
+				err = vm.stack.Push(item)
+				if err != nil {
+					return err
+				}
+				err = vm.run(callback.Code)
+				if err != nil {
+					return err
+				}
+				cellFromCallback, err := vm.stack.Pop()
+				if err != nil {
+					return fmt.Errorf("pop boolean (%d): %w", i, err)
+				}
+				boolean, ok := cellFromCallback.(CellBoolean)
+				if !ok {
+					return fmt.Errorf("got %T instead of boolean (%d)", cellFromCallback, i)
+				}
+				if boolean {
+					out = append(out, item)
+				}
+			}
+			return vm.stack.Push(out)
+		},
+	},
+	{
+		Name: "reduce",
+		Func: func(vm *VM) error {
+			// Pop callback
+			cellC, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (C) callback (quotation): %w", err)
+			}
+			callback, ok := cellC.(CellQuotation)
+			if !ok {
+				return fmt.Errorf("got (C) %T instead of quotation", cellC)
+			}
+
+			// Pop initial value
+			cellB, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (B) initial value: %w", err)
+			}
+
+			// Pop list
+			cellA, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (A) list: %w", err)
+			}
+			list, ok := cellA.(CellList)
+			if !ok {
+				return fmt.Errorf("got (A) %T instead of list", cellA)
+			}
+
+			// Execute callback with the accumulated value and each item,
+			// the accumulated value is left on the stack.
+			err = vm.stack.Push(cellB)
+			if err != nil {
+				return err
+			}
+			for _, item := range list {
+				err = vm.stack.Push(item)
+				if err != nil {
+					return err
+				}
+				err = vm.run(callback.Code)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
 	{
 		Name: "write",
 		Func: func(vm *VM) error {
@@ -200,20 +395,154 @@ var Builtins = []*Definition{
 				return vm.ui.Write(strconv.Itoa(int(a)))
 			case CellFloat:
 				return vm.ui.Write(strconv.FormatFloat(float64(a), 'f', 2, 64))
+			case CellList, CellMap:
+				v, err := formatCell(a, false)
+				if err != nil {
+					return err
+				}
+				return vm.ui.Write(v)
 			}
 			return newInvalidTypeError(cellA)
 		},
 	},
+	newFormatWord("write-bold", StyleBold),
+	newFormatWord("write-italic", StyleItalic),
+	newFormatWord("write-code", StyleCode),
+	newFormatWord("write-item", StyleListItem),
+	{
+		Name: "write-link",
+		Func: func(vm *VM) error {
+			// Pop URL
+			cellB, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (B) URL (text): %w", err)
+			}
+			u, ok := cellB.(CellText)
+			if !ok {
+				return fmt.Errorf("got (B) %T instead of text", cellB)
+			}
+			err = ValidateLinkURL(string(u))
+			if err != nil {
+				return err
+			}
+
+			// Pop link text
+			cellA, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (A) link text (text): %w", err)
+			}
+			text, ok := cellA.(CellText)
+			if !ok {
+				return fmt.Errorf("got (A) %T instead of text", cellA)
+			}
+			return WriteFormatted(vm.ui, Message{{Text: string(text), Style: StyleLink, URL: string(u)}})
+		},
+	},
 	{
 		Name: "read",
 		Func: func(vm *VM) error {
-			line, err := vm.ui.Read()
+			// Lines that don't fit in the memory budget are rejected while reading if the UI supports it
+			var line string
+			var err error
+			if ui, ok := vm.ui.(BoundedReader); ok && vm.stack.available() >= 0 {
+				line, err = ui.ReadMax(vm.stack.available())
+			} else {
+				line, err = vm.ui.Read()
+			}
 			if err != nil {
 				return err
 			}
+			vm.trackInput(line)
 			return vm.stack.Push(CellText(line))
 		},
 	},
+	{
+		Name: "ask-choice",
+		Func: func(vm *VM) error {
+			// Pop options
+			cellB, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (B) options (list): %w", err)
+			}
+			list, ok := cellB.(CellList)
+			if !ok {
+				return fmt.Errorf("got (B) %T instead of list", cellB)
+			}
+			options := make([]string, len(list))
+			for i, c := range list {
+				option, ok := c.(CellText)
+				if !ok {
+					return fmt.Errorf("got option %d %T instead of text", i, c)
+				}
+				options[i] = string(option)
+			}
+
+			// Pop prompt
+			prompt, err := popPrompt(vm)
+			if err != nil {
+				return err
+			}
+
+			i, err := richUI(vm.ui).Choose(prompt, options)
+			if err != nil {
+				return err
+			}
+			if i < 0 || i >= len(options) {
+				return fmt.Errorf("%w: chosen option %d", ErrIndexOutOfRange, i)
+			}
+			vm.trackInput(options[i])
+			return vm.stack.Push(CellText(options[i]))
+		},
+	},
+	{
+		Name: "ask-confirm",
+		Func: func(vm *VM) error {
+			prompt, err := popPrompt(vm)
+			if err != nil {
+				return err
+			}
+			ok, err := richUI(vm.ui).Confirm(prompt)
+			if err != nil {
+				return err
+			}
+			return vm.stack.Push(CellBoolean(ok))
+		},
+	},
+	{
+		Name: "ask-number",
+		Func: func(vm *VM) error {
+			answer, err := askInput(vm, InputNumber)
+			if err != nil {
+				return err
+			}
+			if n, err := strconv.Atoi(answer); err == nil {
+				return vm.stack.Push(CellInteger(n))
+			}
+			f, _ := strconv.ParseFloat(answer, 64)
+			return vm.stack.Push(CellFloat(f))
+		},
+	},
+	{
+		Name: "ask-date",
+		Func: func(vm *VM) error {
+			answer, err := askInput(vm, InputDate)
+			if err != nil {
+				return err
+			}
+			t, _ := time.Parse(DateLayout, answer)
+			return vm.stack.Push(CellTime(t))
+		},
+	},
+	{
+		Name: "ask-email",
+		Func: func(vm *VM) error {
+			answer, err := askInput(vm, InputEmail)
+			if err != nil {
+				return err
+			}
+			return vm.stack.Push(CellText(answer))
+		},
+	},
 	{
 		Name: "random",
 		Func: func(vm *VM) error {
@@ -235,11 +564,13 @@ var Builtins = []*Definition{
 		},
 	},
 	{
-		Name: "now",
-		Func: func(vm *VM) error { return vm.stack.Push(CellTime(time.Now())) },
+		Name:       "now",
+		Capability: CapabilityTimers,
+		Func:       func(vm *VM) error { return vm.stack.Push(CellTime(time.Now())) },
 	},
 	{
-		Name: "wait",
+		Name:       "wait",
+		Capability: CapabilityTimers,
 		Func: func(vm *VM) error {
 			cellA, err := vm.stack.Pop()
 			if err != nil {
@@ -249,17 +580,17 @@ var Builtins = []*Definition{
 			default:
 				return newInvalidTypeError(a)
 			case CellInteger:
-				time.Sleep(time.Duration(a) * time.Millisecond)
+				return vm.sleep(time.Duration(a) * time.Millisecond)
 			case CellFloat:
-				time.Sleep(time.Duration(float64(a) * float64(time.Second)))
+				return vm.sleep(time.Duration(float64(a) * float64(time.Second)))
 			case CellTime:
-				time.Sleep(time.Until(time.Time(a)))
+				return vm.sleep(time.Until(time.Time(a)))
 			}
-			return nil
 		},
 	},
 	{
-		Name: "retrieve",
+		Name:       "retrieve",
+		Capability: CapabilityNetwork,
 		Func: func(vm *VM) error {
 			if vm.conn == nil {
 				return errors.New("not connected to server")
@@ -270,10 +601,112 @@ var Builtins = []*Definition{
 			}
 			switch a := cellA.(type) {
 			case CellText:
-				_, err = jutp.Write(vm.conn, jutp.Message(a))
-				return err
+				return vm.upload(string(a))
+			case CellList, CellMap:
+				v, err := formatCell(a, false)
+				if err != nil {
+					return err
+				}
+				return vm.upload(v)
 			}
 			return newInvalidTypeError(cellA)
 		},
 	},
+	{
+		Name:       "store-set",
+		Capability: CapabilityStorage,
+		Func: func(vm *VM) error {
+			cellB, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (B) value: %w", err)
+			}
+			cellA, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (A) key (text): %w", err)
+			}
+			key, ok := cellA.(CellText)
+			if !ok {
+				return fmt.Errorf("got (A) %T instead of text", cellA)
+			}
+			value, err := encodeJSON(cellB)
+			if err != nil {
+				return err
+			}
+			return vm.store.Set(string(key), value)
+		},
+	},
+	{
+		Name:       "store-get",
+		Capability: CapabilityStorage,
+		Func: func(vm *VM) error {
+			cellA, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (A) key (text): %w", err)
+			}
+			key, ok := cellA.(CellText)
+			if !ok {
+				return fmt.Errorf("got (A) %T instead of text", cellA)
+			}
+			value, err := vm.store.Get(string(key))
+			if err != nil {
+				return err
+			}
+			c, err := decodeJSON(value)
+			if err != nil {
+				return err
+			}
+			return vm.stack.Push(c)
+		},
+	},
+	{
+		Name:       "store-has",
+		Capability: CapabilityStorage,
+		Func: func(vm *VM) error {
+			cellA, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (A) key (text): %w", err)
+			}
+			key, ok := cellA.(CellText)
+			if !ok {
+				return fmt.Errorf("got (A) %T instead of text", cellA)
+			}
+			_, err = vm.store.Get(string(key))
+			if errors.Is(err, ErrMissingKey) {
+				return vm.stack.Push(CellBoolean(false))
+			} else if err != nil {
+				return err
+			}
+			return vm.stack.Push(CellBoolean(true))
+		},
+	},
+	{
+		Name:       "store-delete",
+		Capability: CapabilityStorage,
+		Func: func(vm *VM) error {
+			cellA, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (A) key (text): %w", err)
+			}
+			key, ok := cellA.(CellText)
+			if !ok {
+				return fmt.Errorf("got (A) %T instead of text", cellA)
+			}
+			return vm.store.Delete(string(key))
+		},
+	},
+	{
+		Name:       "store-keys",
+		Capability: CapabilityStorage,
+		Func: func(vm *VM) error {
+			keys, err := vm.store.Keys()
+			if err != nil {
+				return err
+			}
+			list := make(CellList, len(keys))
+			for i, k := range keys {
+				list[i] = CellText(k)
+			}
+			return vm.stack.Push(list)
+		},
+	},
 }
@@ -0,0 +1,165 @@
+package jul
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Style is the formatting of a span of text in a message.
+type Style string
+
+const (
+	StylePlain    Style = ""
+	StyleBold     Style = "bold"
+	StyleItalic   Style = "italic"
+	StyleCode     Style = "code"
+	StyleLink     Style = "link"
+	StyleListItem Style = "list-item"
+)
+
+// Span is a piece of text with a single style.
+type Span struct {
+	Text  string `json:"text"`
+	Style Style  `json:"style,omitempty"`
+	URL   string `json:"url,omitempty"` // Target of links (http, https or mailto)
+}
+
+// Message is formatted text written to the UI.
+// UIs decide how styles are rendered, so servers can't send raw terminal escape sequences or HTML.
+type Message []Span
+
+// FormattedWriter is implemented by UIs that can render formatted messages.
+type FormattedWriter interface {
+	WriteFormatted(msg Message) error
+}
+
+// WriteFormatted writes a formatted message to the UI,
+// as plain text if the UI can't render formatted messages.
+func WriteFormatted(ui UI, msg Message) error {
+	if fw, ok := ui.(FormattedWriter); ok {
+		return fw.WriteFormatted(msg)
+	}
+	return ui.Write(msg.PlainText())
+}
+
+// PlainText returns the message without styles, links are followed by their URL.
+func (msg Message) PlainText() string {
+	out := ""
+	for _, span := range msg {
+		switch span.Style {
+		default:
+			out += span.Text
+		case StyleCode:
+			out += "`" + span.Text + "`"
+		case StyleLink:
+			out += linkText(span)
+		case StyleListItem:
+			out += listItemText(out, span)
+		}
+	}
+	return out
+}
+
+// ANSI returns the message formatted with ANSI escape sequences, for terminals.
+// Control characters are removed from texts and URLs.
+func (msg Message) ANSI() string {
+	out := ""
+	for _, span := range msg {
+		span.Text, span.URL = sanitizeText(span.Text), sanitizeText(span.URL)
+		switch span.Style {
+		default:
+			out += span.Text
+		case StyleBold:
+			out += "\x1b[1m" + span.Text + "\x1b[22m"
+		case StyleItalic:
+			out += "\x1b[3m" + span.Text + "\x1b[23m"
+		case StyleCode:
+			out += "\x1b[36m" + span.Text + "\x1b[39m"
+		case StyleLink:
+			out += "\x1b[4m" + linkText(span) + "\x1b[24m"
+		case StyleListItem:
+			out += listItemText(out, span)
+		}
+	}
+	return out
+}
+
+// linkText returns the text of a link followed by its URL (if different).
+func linkText(span Span) string {
+	if span.Text == "" || span.Text == span.URL {
+		return span.URL
+	}
+	return span.Text + " (" + span.URL + ")"
+}
+
+// listItemText returns a list item ending with a new line, preceded by a new line if needed within the message.
+func listItemText(before string, span Span) string {
+	out := "- " + span.Text + "\n"
+	if before != "" && !strings.HasSuffix(before, "\n") {
+		out = "\n" + out
+	}
+	return out
+}
+
+// sanitizeText removes control characters (like escape sequences) except tabs and new lines.
+func sanitizeText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// ValidateLinkURL returns an error if the URL can't be used in a link.
+// Only absolute http, https and mailto URLs are allowed.
+func ValidateLinkURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", s, err)
+	}
+	switch u.Scheme {
+	default:
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	case "http", "https":
+		if u.Host == "" {
+			return fmt.Errorf("missing host in URL %q", s)
+		}
+	case "mailto":
+	}
+	if sanitizeText(s) != s {
+		return fmt.Errorf("invalid URL %q: contains control characters", s)
+	}
+	return nil
+}
+
+// isTerminal returns true if w is a terminal that supports ANSI styles.
+func isTerminal(w any) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// newFormatWord returns a word that writes the text on top of the stack with the given style.
+func newFormatWord(name string, style Style) *Definition {
+	return &Definition{
+		Name: name,
+		Func: func(vm *VM) error {
+			cellA, err := vm.stack.Pop()
+			if err != nil {
+				return fmt.Errorf("pop (A) text: %w", err)
+			}
+			text, ok := cellA.(CellText)
+			if !ok {
+				return fmt.Errorf("got (A) %T instead of text", cellA)
+			}
+			return WriteFormatted(vm.ui, Message{{Text: string(text), Style: style}})
+		},
+	}
+}
@@ -0,0 +1,65 @@
+package jul
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	msg := Message{
+		{Text: "Total: "},
+		{Text: "12.50", Style: StyleBold},
+		{Text: "Track", Style: StyleLink, URL: "https://example.com/1"},
+		{Text: "one", Style: StyleListItem},
+		{Text: "go run", Style: StyleCode},
+		{Text: "\x1b[2Jcleared", Style: StyleItalic},
+	}
+
+	got := msg.PlainText()
+	want := "Total: 12.50Track (https://example.com/1)\n- one\n`go run`\x1b[2Jcleared"
+	if got != want {
+		t.Fatalf("got plain text %q instead of %q", got, want)
+	}
+
+	got = msg.ANSI()
+	want = "Total: \x1b[1m12.50\x1b[22m\x1b[4mTrack (https://example.com/1)\x1b[24m\n- one\n\x1b[36mgo run\x1b[39m\x1b[3m[2Jcleared\x1b[23m"
+	if got != want {
+		t.Fatalf("got ANSI text %q instead of %q", got, want)
+	}
+}
+
+func TestFormatWords(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: `"Price: " write "10" write-bold`, want: "Price: 10"},
+		{code: `*Track "https://example.com" write-link`, want: "Track (https://example.com)"},
+		{code: `"Menu:\n" write *Pizza write-item *Pasta write-item`, want: "Menu:\n- Pizza\n- Pasta"},
+		{code: `"ls" write-code "!" write-italic`, want: "`ls`!"},
+	}
+	for _, test := range tests {
+		err := RunTranscript("test", strings.NewReader(test.code), []TranscriptStep{TranscriptOutput(test.want)})
+		if err != nil {
+			t.Fatalf("%s: %s", test.code, err)
+		}
+	}
+
+	for _, code := range []string{`*Click "javascript:alert(1)" write-link`, `*Click "/relative" write-link`} {
+		err := NewVM(WithUI(NewScriptedUI())).Execute(strings.NewReader(code))
+		if err == nil {
+			t.Fatalf("got no error for %q", code)
+		}
+	}
+}
+
+func TestDefaultUIRemovesControlCharacters(t *testing.T) {
+	out := &strings.Builder{}
+	err := NewDefaultUI(nil, out).Write("a\x1b[31mb\x07\tc\n")
+	if err != nil {
+		panic(err)
+	}
+	if out.String() != "a[31mb\tc\n" {
+		t.Fatalf("got %q", out)
+	}
+}
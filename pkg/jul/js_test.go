@@ -0,0 +1,86 @@
+//go:build js && wasm
+
+package jul
+
+import (
+	"bufio"
+	"strings"
+	"syscall/js"
+	"testing"
+
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+// Run with: GOOS=js GOARCH=wasm go test ./pkg/jul (with go_js_wasm_exec in the PATH)
+func TestJS(t *testing.T) {
+	t.Run("executes scripts with a JavaScript UI and transport", func(t *testing.T) {
+		var written, sent []string
+		funcs := map[string]func(args []js.Value) any{
+			"write": func(args []js.Value) any { written = append(written, args[0].String()); return nil },
+			"read":  func(args []js.Value) any { return js.Global().Get("Promise").Call("resolve", "Ju") },
+			"confirmUpload": func(args []js.Value) any {
+				return args[0].Get("data").String() == "Hello, Ju" && args[0].Get("userInputs").Index(0).String() == "Ju"
+			},
+			"choose": func(args []js.Value) any { return 1 },
+			"send":   func(args []js.Value) any { sent = append(sent, args[0].String()); return nil },
+		}
+		obj := js.Global().Get("Object").New()
+		for name, f := range funcs {
+			f := f
+			fn := js.FuncOf(func(this js.Value, args []js.Value) any { return f(args) })
+			defer fn.Release()
+			obj.Set(name, fn)
+		}
+
+		conn := NewJSConn(obj, "example.com")
+		vm := NewVM(WithUI(NewJSUI(obj)), WithServerConnection(conn))
+		code := `"Name? " write "Hello, " read add retrieve "Pick one" {"a" "b"} ask-choice write`
+		err := vm.Execute(strings.NewReader(code))
+		if err != nil {
+			panic(err)
+		}
+		if got := strings.Join(written, "|"); got != "Name? |b" {
+			t.Fatalf("got written %q instead of %q", got, "Name? |b")
+		}
+		if len(sent) != 1 || sent[0] != "Hello, Ju" {
+			t.Fatalf("got sent messages %q", sent)
+		}
+		if conn.RemoteAddr().String() != "example.com" {
+			t.Fatalf("got origin %q instead of %q", conn.RemoteAddr(), "example.com")
+		}
+	})
+
+	t.Run("reads received messages until closed", func(t *testing.T) {
+		conn := NewJSConn(js.Global().Get("Object").New(), "example.com")
+		conn.Receive("a")
+		conn.Receive("b")
+		conn.Close()
+		r := bufio.NewReader(conn)
+		for _, want := range []jutp.Message{"a", "b"} {
+			msg, err := jutp.Read(r)
+			if err != nil {
+				panic(err)
+			}
+			if msg != want {
+				t.Fatalf("got message %q instead of %q", msg, want)
+			}
+		}
+		_, err := jutp.Read(r)
+		if err == nil {
+			t.Fatal("got nil error after close")
+		}
+	})
+
+	t.Run("returns rejected promises as errors", func(t *testing.T) {
+		reject := js.FuncOf(func(this js.Value, args []js.Value) any {
+			return js.Global().Get("Promise").Call("reject", js.Global().Get("Error").New("offline"))
+		})
+		defer reject.Release()
+		obj := js.Global().Get("Object").New()
+		obj.Set("read", reject)
+		_, err := NewJSUI(obj).Read()
+		if err == nil || err.Error() != "offline" {
+			t.Fatalf("got error %v instead of %q", err, "offline")
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package jul
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrUnsupportedJSON = errors.New("unsupported JSON value")
+
+func (s *Stack) ToJSON() error {
+	cellA, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	b, err := encodeJSON(cellA)
+	if err != nil {
+		return err
+	}
+	return s.Push(CellText(b))
+}
+
+func (s *Stack) FromJSON() error {
+	cellA, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	text, ok := cellA.(CellText)
+	if !ok {
+		return newInvalidTypeError(cellA)
+	}
+	c, err := decodeJSON([]byte(text))
+	if err != nil {
+		return err
+	}
+	return s.Push(c)
+}
+
+// encodeJSON returns the JSON encoding of a cell.
+func encodeJSON(c any) ([]byte, error) {
+	v, err := toJSONValue(c)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// decodeJSON returns the cell encoded in the given JSON document.
+func decodeJSON(b []byte) (any, error) {
+	// Decode numbers as json.Number to distinguish integers from floats
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	if err != nil {
+		return nil, fmt.Errorf("decode JSON: %w", err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("decode JSON: unexpected data after top-level value")
+	}
+	return fromJSONValue(v)
+}
+
+// toJSONValue converts a cell to a value that can be encoded with encoding/json.
+// Times are encoded as RFC 3339 text, quotations can't be encoded.
+func toJSONValue(c any) (any, error) {
+	switch c := c.(type) {
+	default:
+		return nil, fmt.Errorf("%w: can't encode %T", ErrUnsupportedJSON, c)
+	case CellBoolean:
+		return bool(c), nil
+	case CellInteger:
+		return int(c), nil
+	case CellFloat:
+		return float64(c), nil
+	case CellText:
+		return string(c), nil
+	case CellTime:
+		return time.Time(c).Format(time.RFC3339Nano), nil
+	case CellList:
+		out := make([]any, len(c))
+		for i, item := range c {
+			v, err := toJSONValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("list item %d: %w", i, err)
+			}
+			out[i] = v
+		}
+		return out, nil
+	case CellMap:
+		out := make(map[string]any, len(c))
+		for k, item := range c {
+			v, err := toJSONValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("map key %q: %w", k, err)
+			}
+			out[k] = v
+		}
+		return out, nil
+	}
+}
+
+// fromJSONValue converts a value decoded by encoding/json to a cell.
+// Numbers without fraction or exponent are converted to integers, null is not supported.
+func fromJSONValue(v any) (any, error) {
+	switch v := v.(type) {
+	default:
+		return nil, fmt.Errorf("%w: can't decode %T", ErrUnsupportedJSON, v)
+	case nil:
+		return nil, fmt.Errorf("%w: can't decode null", ErrUnsupportedJSON)
+	case bool:
+		return CellBoolean(v), nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return CellInteger(n), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedJSON, err)
+		}
+		return CellFloat(f), nil
+	case string:
+		return CellText(v), nil
+	case []any:
+		out := make(CellList, len(v))
+		for i, item := range v {
+			c, err := fromJSONValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("list item %d: %w", i, err)
+			}
+			out[i] = c
+		}
+		return out, nil
+	case map[string]any:
+		out := make(CellMap, len(v))
+		for k, item := range v {
+			c, err := fromJSONValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("map key %q: %w", k, err)
+			}
+			out[k] = c
+		}
+		return out, nil
+	}
+}
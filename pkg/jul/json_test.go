@@ -0,0 +1,53 @@
+package jul
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		desc   string
+		input  string
+		output any
+	}{
+		{desc: "to-json text", input: `"a\"b" to-json`, output: CellText(`"a\"b"`)},
+		{desc: "to-json list", input: "{ 1 true *a } to-json", output: CellText(`[1,true,"a"]`)},
+		{desc: "to-json map", input: "#{ *b { 1 } *a 2 } to-json", output: CellText(`{"a":2,"b":[1]}`)},
+		{desc: "from-json integer", input: `"42" from-json`, output: CellInteger(42)},
+		{desc: "from-json float", input: `"1.5" from-json`, output: CellFloat(1.5)},
+		{desc: "from-json object", input: `"{\"name\": \"Ju\", \"tags\": [\"a\", false]}" from-json`, output: CellMap{
+			"name": CellText("Ju"),
+			"tags": CellList{CellText("a"), CellBoolean(false)},
+		}},
+		{desc: "round-trip", input: "#{ *a { 1 *b } } dup to-json from-json is-equal", output: CellBoolean(true)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			vm := NewVM()
+			err := vm.Execute(strings.NewReader(test.input))
+			if err != nil {
+				panic(err)
+			}
+			c, err := vm.stack.Pop()
+			if err != nil {
+				panic(err)
+			}
+			if !isEqualCell(c, test.output) {
+				t.Fatalf("got %#v instead of %#v", c, test.output)
+			}
+		})
+	}
+
+	for _, input := range []string{"{ [noop] } to-json", `"null" from-json`, `"[1, null]" from-json`} {
+		t.Run("fails on unsupported value: "+input, func(t *testing.T) {
+			vm := NewVM()
+			err := vm.Execute(strings.NewReader(input))
+			if !errors.Is(err, ErrUnsupportedJSON) {
+				t.Fatalf("got error %v instead of %v", err, ErrUnsupportedJSON)
+			}
+		})
+	}
+}
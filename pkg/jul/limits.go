@@ -0,0 +1,77 @@
+package jul
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultMaxCallDepth is the maximum number of nested word calls, unless set with WithMaxCallDepth.
+const DefaultMaxCallDepth = 10_000
+
+// ErrLimitExceeded is wrapped by the errors returned when an execution limit is reached,
+// as opposed to errors raised by the script itself.
+var ErrLimitExceeded = errors.New("execution limit exceeded")
+
+var (
+	ErrStepLimit      = fmt.Errorf("%w: too many steps", ErrLimitExceeded)
+	ErrCallDepthLimit = fmt.Errorf("%w: too many nested calls", ErrLimitExceeded)
+	ErrTimeout        = fmt.Errorf("%w: timeout", ErrLimitExceeded)
+	ErrMemoryLimit    = fmt.Errorf("%w: out of memory", ErrLimitExceeded)
+)
+
+// WithMaxSteps sets the maximum number of instructions executed by a call to Execute (0 means no limit).
+func WithMaxSteps(n int) Option { return func(vm *VM) { vm.maxSteps = n } }
+
+// WithTimeout sets the maximum duration of a call to Execute (0 means no limit).
+func WithTimeout(d time.Duration) Option { return func(vm *VM) { vm.timeout = d } }
+
+// WithMaxCallDepth sets the maximum number of nested word calls (0 means no limit).
+func WithMaxCallDepth(n int) Option { return func(vm *VM) { vm.maxCallDepth = n } }
+
+// WithMaxMemory sets the memory budget of the stack in bytes (0 means no limit).
+// Texts, lists and maps that would exceed it are rejected with ErrMemoryLimit, see Stack.SetMemoryLimit.
+func WithMaxMemory(n int) Option { return func(vm *VM) { vm.maxMemory = n } }
+
+// step counts an executed instruction and checks the execution limits.
+// The context is checked when entering a quotation (so in every loop iteration)
+// and every 1024 instructions.
+func (vm *VM) step(enter bool) error {
+	vm.steps++
+	if vm.maxSteps > 0 && vm.steps > vm.maxSteps {
+		return ErrStepLimit
+	}
+	if vm.steps%1024 == 0 {
+		yield()
+	}
+	if vm.done != nil && (enter || vm.steps%1024 == 0) {
+		return vm.checkContext()
+	}
+	return nil
+}
+
+// checkContext returns ErrTimeout if the VM timeout is reached,
+// or the context error if the execution was cancelled by the caller.
+func (vm *VM) checkContext() error {
+	select {
+	default:
+		return nil
+	case <-vm.done:
+	}
+	if !vm.deadline.IsZero() && !time.Now().Before(vm.deadline) {
+		return ErrTimeout
+	}
+	return vm.ctx.Err()
+}
+
+// sleep pauses the execution, it returns early if the execution is cancelled.
+func (vm *VM) sleep(d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-vm.done:
+		return vm.checkContext()
+	}
+}
@@ -0,0 +1,100 @@
+package jul
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecutionLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		opts []Option
+		want error
+	}{
+		{name: "step limit", code: "[drop true] repeat", opts: []Option{WithMaxSteps(10_000)}, want: ErrStepLimit},
+		{name: "timeout", code: "[drop true] repeat", opts: []Option{WithTimeout(20 * time.Millisecond)}, want: ErrTimeout},
+		{name: "timeout while waiting", code: "10000 wait", opts: []Option{WithTimeout(20 * time.Millisecond)}, want: ErrTimeout},
+		{name: "call depth limit", code: "*f [f] define f", opts: []Option{WithMaxCallDepth(100)}, want: ErrCallDepthLimit},
+		{name: "default call depth limit", code: "*f [f] define f", want: ErrCallDepthLimit},
+		{name: "text memory limit", code: `"ab" [drop dup add true] repeat`, opts: []Option{WithMaxMemory(1 << 20)}, want: ErrMemoryLimit},
+		{name: "list memory limit", code: `{} [drop "abc" append true] repeat`, opts: []Option{WithMaxMemory(1 << 16)}, want: ErrMemoryLimit},
+		{name: "nested quotations", code: "*f [[drop true] repeat] define [f] do", opts: []Option{WithMaxSteps(10_000)}, want: ErrStepLimit},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vm := NewVM(append(test.opts, WithUI(NewDefaultUI(nil, io.Discard)))...)
+			err := vm.Execute(strings.NewReader(test.code))
+			if !errors.Is(err, test.want) || !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("got error %v instead of %v", err, test.want)
+			}
+		})
+	}
+
+	t.Run("script errors are not limit errors", func(t *testing.T) {
+		err := NewVM(WithMaxSteps(10)).Execute(strings.NewReader("unknown-word"))
+		if err == nil || errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("got error %v", err)
+		}
+	})
+
+	t.Run("frees memory of dropped cells", func(t *testing.T) {
+		vm := NewVM(WithMaxMemory(1024), WithMaxSteps(10_000))
+		err := vm.Execute(strings.NewReader(`[drop "abcdefghijklmnopqrstuvwxyz" drop true] repeat`))
+		if !errors.Is(err, ErrStepLimit) {
+			t.Fatalf("got error %v instead of %v", err, ErrStepLimit)
+		}
+	})
+
+	t.Run("rejects long user input", func(t *testing.T) {
+		ui := NewDefaultUI(strings.NewReader(strings.Repeat("a", 10_000)+"\nok\n"), io.Discard)
+		vm := NewVM(WithUI(ui), WithMaxMemory(1024))
+		err := vm.Execute(strings.NewReader("read"))
+		if !errors.Is(err, ErrMemoryLimit) {
+			t.Fatalf("got error %v instead of %v", err, ErrMemoryLimit)
+		}
+		err = vm.Execute(strings.NewReader(`read "ok" is-equal`))
+		if err != nil {
+			panic(err)
+		}
+		if c, _ := vm.stack.Pop(); c != CellBoolean(true) {
+			t.Fatalf("got %v instead of true, the rest of the long line should be discarded", c)
+		}
+	})
+
+	t.Run("limits apply to each execution", func(t *testing.T) {
+		vm := NewVM(WithMaxSteps(10))
+		for i := 0; i < 3; i++ {
+			err := vm.Execute(strings.NewReader("1 2 add drop"))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("stops when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		for _, code := range []string{"[drop true] repeat", "10000 wait"} {
+			err := NewVM().ExecuteContext(ctx, strings.NewReader(code))
+			if !errors.Is(err, context.Canceled) || errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("got error %v instead of %v", err, context.Canceled)
+			}
+		}
+	})
+
+	t.Run("elides deep call stacks", func(t *testing.T) {
+		err := NewVM().Execute(strings.NewReader("*f [f] define f"))
+		var rerr RuntimeError
+		if !errors.As(err, &rerr) {
+			t.Fatalf("got error %v instead of runtime error", err)
+		}
+		if n := strings.Count(rerr.StackTrace(), "\n\t"); n != maxStackTraceFrames {
+			t.Fatalf("got %d frames instead of %d", n, maxStackTraceFrames)
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package jul
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// LineEditor reads lines from a terminal with basic line editing:
+// arrow keys, Home/End and Ctrl-A/Ctrl-E move the cursor, Backspace, Delete, Ctrl-U, Ctrl-K and Ctrl-W delete text,
+// Up/Down and Ctrl-P/Ctrl-N navigate through previous lines. Ctrl-D on an empty line and Ctrl-C end the input.
+//
+// The terminal is in raw mode only while a line is being edited, so that output is written as usual in between.
+// If the input is not a terminal, it is read unchanged.
+type LineEditor struct {
+	History []string // Previous lines, oldest first (lines read are appended)
+
+	in   *os.File
+	keys *bufio.Reader
+	w    io.Writer
+	buf  []byte // Rest of the last line read, returned by the next calls to Read
+	raw  bool   // False if the input is not a terminal
+}
+
+// NewLineEditor returns an editor reading keys from in and echoing the edited line to w.
+func NewLineEditor(in *os.File, w io.Writer) *LineEditor {
+	return &LineEditor{in: in, keys: bufio.NewReader(in), w: w, raw: term.IsTerminal(int(in.Fd()))}
+}
+
+// Read returns the edited lines, each followed by a line feed.
+func (e *LineEditor) Read(p []byte) (int, error) {
+	if len(e.buf) == 0 {
+		if !e.raw {
+			return e.keys.Read(p)
+		}
+		state, err := term.MakeRaw(int(e.in.Fd()))
+		if err != nil {
+			e.raw = false
+			return e.keys.Read(p)
+		}
+		line, err := e.editLine()
+		_ = term.Restore(int(e.in.Fd()), state)
+		if err != nil {
+			return 0, err
+		}
+		e.buf = append([]byte(line), '\n')
+	}
+	n := copy(p, e.buf)
+	e.buf = e.buf[n:]
+	return n, nil
+}
+
+// Keys that aren't characters.
+const (
+	keyUnknown rune = -1 - iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+)
+
+// readKey returns the next character or key, escape sequences are decoded.
+func (e *LineEditor) readKey() (rune, error) {
+	r, _, err := e.keys.ReadRune()
+	if err != nil || r != '\x1b' {
+		return r, err
+	}
+
+	// Escape sequences are ESC, "[" or "O", optional parameters and a final byte
+	b, err := e.keys.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != '[' && b != 'O' {
+		return keyUnknown, nil
+	}
+	var params []byte
+	for {
+		b, err = e.keys.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b >= 0x40 && b <= 0x7e {
+			break
+		}
+		params = append(params, b)
+	}
+	switch {
+	case b == 'A':
+		return keyUp, nil
+	case b == 'B':
+		return keyDown, nil
+	case b == 'C':
+		return keyRight, nil
+	case b == 'D':
+		return keyLeft, nil
+	case b == 'H', b == '~' && (string(params) == "1" || string(params) == "7"):
+		return keyHome, nil
+	case b == 'F', b == '~' && (string(params) == "4" || string(params) == "8"):
+		return keyEnd, nil
+	case b == '~' && string(params) == "3":
+		return keyDelete, nil
+	}
+	return keyUnknown, nil
+}
+
+// editLine reads keys until Enter is pressed and returns the line, which is added to the history.
+func (e *LineEditor) editLine() (string, error) {
+	var line []rune
+	pos := 0                // Cursor position in the line
+	index := len(e.History) // Position in the history, the line being edited is last
+	draft := ""             // Line being edited, kept while navigating through the history
+
+	// show redraws the line after an edit, the cursor was at the given position.
+	show := func(prev int) error {
+		var out string
+		if prev > 0 {
+			out = fmt.Sprintf("\x1b[%dD", prev)
+		}
+		out += string(line) + "\x1b[K"
+		if n := len(line) - pos; n > 0 {
+			out += fmt.Sprintf("\x1b[%dD", n)
+		}
+		_, err := io.WriteString(e.w, out)
+		return err
+	}
+	recall := func(i int) error {
+		if index == len(e.History) {
+			draft = string(line)
+		}
+		index = i
+		prev := pos
+		if index == len(e.History) {
+			line = []rune(draft)
+		} else {
+			line = []rune(e.History[index])
+		}
+		pos = len(line)
+		return show(prev)
+	}
+
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+		prev := pos
+		switch key {
+		default:
+			if key < 0 || !unicode.IsPrint(key) {
+				continue
+			}
+			line = append(line[:pos], append([]rune{key}, line[pos:]...)...)
+			pos++
+		case '\r', '\n':
+			_, err = io.WriteString(e.w, "\r\n")
+			if err != nil {
+				return "", err
+			}
+			if len(line) > 0 && (len(e.History) == 0 || e.History[len(e.History)-1] != string(line)) {
+				e.History = append(e.History, string(line))
+			}
+			return string(line), nil
+		case '\x03': // Ctrl-C
+			_, _ = io.WriteString(e.w, "^C\r\n")
+			return "", io.EOF
+		case '\x04': // Ctrl-D
+			if len(line) == 0 {
+				_, _ = io.WriteString(e.w, "\r\n")
+				return "", io.EOF
+			}
+			if pos == len(line) {
+				continue
+			}
+			line = append(line[:pos], line[pos+1:]...)
+		case keyDelete:
+			if pos == len(line) {
+				continue
+			}
+			line = append(line[:pos], line[pos+1:]...)
+		case '\x7f', '\b': // Backspace
+			if pos == 0 {
+				continue
+			}
+			line = append(line[:pos-1], line[pos:]...)
+			pos--
+		case '\x17': // Ctrl-W deletes the word before the cursor
+			start := pos
+			for start > 0 && unicode.IsSpace(line[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(line[start-1]) {
+				start--
+			}
+			line = append(line[:start], line[pos:]...)
+			pos = start
+		case '\x15': // Ctrl-U
+			line = line[pos:]
+			pos = 0
+		case '\x0b': // Ctrl-K
+			line = line[:pos]
+		case keyLeft, '\x02': // Ctrl-B
+			if pos > 0 {
+				pos--
+			}
+		case keyRight, '\x06': // Ctrl-F
+			if pos < len(line) {
+				pos++
+			}
+		case keyHome, '\x01': // Ctrl-A
+			pos = 0
+		case keyEnd, '\x05': // Ctrl-E
+			pos = len(line)
+		case keyUp, '\x10': // Ctrl-P
+			if index > 0 {
+				err = recall(index - 1)
+				if err != nil {
+					return "", err
+				}
+			}
+			continue
+		case keyDown, '\x0e': // Ctrl-N
+			if index < len(e.History) {
+				err = recall(index + 1)
+				if err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+		err = show(prev)
+		if err != nil {
+			return "", err
+		}
+	}
+}
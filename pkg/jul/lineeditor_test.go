@@ -0,0 +1,87 @@
+package jul
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLineEditor(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		line string
+	}{
+		{name: "types text", keys: "1 2 add\r", line: "1 2 add"},
+		{name: "inserts at the cursor", keys: "ac\x1b[Db\r", line: "abc"},
+		{name: "deletes before the cursor", keys: "abc\x7f\x7fx\r", line: "ax"},
+		{name: "deletes at the cursor", keys: "abc\x1b[H\x1b[3~\r", line: "bc"},
+		{name: "deletes the previous word", keys: "one two\x17three\r", line: "one three"},
+		{name: "deletes to the start and end", keys: "abc\x1b[D\x0bd\x01\x1b[C\x15e\r", line: "ebd"},
+		{name: "moves to the start and end", keys: "abc\x01x\x05y\r", line: "xabcy"},
+		{name: "recalls previous lines", keys: "\x1b[A\x1b[A\r", line: "1 2"},
+		{name: "restores the edited line", keys: "ab\x1b[A\x1b[B\r", line: "ab"},
+		{name: "edits recalled lines", keys: "\x10\x7f4\r", line: "4"},
+		{name: "ignores unknown keys", keys: "a\x1b[15~\x1bxb\x07\r", line: "ab"},
+		{name: "reads unicode", keys: "héé\x7fllo\r", line: "héllo"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := &LineEditor{History: []string{"1 2", "3"}, keys: bufio.NewReader(strings.NewReader(test.keys)), w: io.Discard}
+			line, err := e.editLine()
+			if err != nil {
+				panic(err)
+			}
+			if line != test.line {
+				t.Fatalf("got line %q instead of %q", line, test.line)
+			}
+			if last := e.History[len(e.History)-1]; last != test.line {
+				t.Fatalf("got last history entry %q instead of %q", last, test.line)
+			}
+		})
+	}
+
+	t.Run("ends the input with Ctrl-D on an empty line", func(t *testing.T) {
+		e := &LineEditor{keys: bufio.NewReader(strings.NewReader("\x04")), w: io.Discard}
+		if _, err := e.editLine(); !errors.Is(err, io.EOF) {
+			t.Fatalf("got error %v instead of %v", err, io.EOF)
+		}
+	})
+
+	t.Run("redraws the line after edits", func(t *testing.T) {
+		out := &strings.Builder{}
+		e := &LineEditor{keys: bufio.NewReader(strings.NewReader("ab\x1b[Dc\r")), w: out}
+		_, err := e.editLine()
+		if err != nil {
+			panic(err)
+		}
+		want := "a\x1b[K" + "\x1b[1Dab\x1b[K" + "\x1b[2Dab\x1b[K\x1b[1D" + "\x1b[1Dacb\x1b[K\x1b[1D" + "\r\n"
+		if out.String() != want {
+			t.Fatalf("got output %q instead of %q", out.String(), want)
+		}
+	})
+
+	t.Run("reads input unchanged when it's not a terminal", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "input")
+		err := os.WriteFile(path, []byte("1 2\x1b[D add\n"), 0o600)
+		if err != nil {
+			panic(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		b, err := io.ReadAll(NewLineEditor(f, io.Discard))
+		if err != nil {
+			panic(err)
+		}
+		if string(b) != "1 2\x1b[D add\n" {
+			t.Fatalf("got input %q", b)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package jul
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// CellList is an ordered collection of cells.
+// Lists are never modified in place, words operating on lists push a new list.
+type CellList []any
+
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+// Collect pops all cells above the given stack depth and returns them as a list (bottom cell first).
+func (s *Stack) Collect(depth int) (CellList, error) {
+	if depth > len(s.cells) {
+		return nil, fmt.Errorf("%w: list body consumed %d cell(s)", ErrStackUnderflow, depth-len(s.cells))
+	}
+	list := make(CellList, len(s.cells)-depth)
+	copy(list, s.cells[depth:])
+	for _, inputs := range s.inputs[depth:] {
+		s.track(inputs...)
+	}
+	s.cells, s.inputs = s.cells[:depth], s.inputs[:depth]
+	if s.maxMemory > 0 {
+		for _, c := range list {
+			s.memory -= cellSize(c)
+		}
+	}
+	return list, nil
+}
+
+func (s *Stack) Length() error {
+	cellA, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	switch a := cellA.(type) {
+	default:
+		return newInvalidTypeError(a)
+	case CellList:
+		return s.Push(CellInteger(len(a)))
+	case CellMap:
+		return s.Push(CellInteger(len(a)))
+	case CellText:
+		return s.Push(CellInteger(utf8.RuneCountInString(string(a))))
+	}
+}
+
+func (s *Stack) Get() error {
+	cellB, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	cellA, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	list, ok := cellA.(CellList)
+	if !ok {
+		return newInvalidTypeError(cellA)
+	}
+	i, ok := cellB.(CellInteger)
+	if !ok {
+		return newInvalidTypeError(cellB)
+	}
+	if i < 0 || int(i) >= len(list) {
+		return fmt.Errorf("%w: %d (length %d)", ErrIndexOutOfRange, i, len(list))
+	}
+	return s.Push(list[i])
+}
+
+func (s *Stack) Append() error {
+	cellB, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	cellA, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	list, ok := cellA.(CellList)
+	if !ok {
+		return newInvalidTypeError(cellA)
+	}
+	out := make(CellList, len(list), len(list)+1)
+	copy(out, list)
+	return s.Push(append(out, cellB))
+}
+
+func (s *Stack) Slice() error {
+	cellC, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	cellB, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	cellA, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	list, ok := cellA.(CellList)
+	if !ok {
+		return newInvalidTypeError(cellA)
+	}
+	from, ok := cellB.(CellInteger)
+	if !ok {
+		return newInvalidTypeError(cellB)
+	}
+	to, ok := cellC.(CellInteger)
+	if !ok {
+		return newInvalidTypeError(cellC)
+	}
+	if from < 0 || to < from || int(to) > len(list) {
+		return fmt.Errorf("%w: [%d:%d] (length %d)", ErrIndexOutOfRange, from, to, len(list))
+	}
+	out := make(CellList, to-from)
+	copy(out, list[from:to])
+	return s.Push(out)
+}
+
+func (s *Stack) Sort() error {
+	cellA, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	list, ok := cellA.(CellList)
+	if !ok {
+		return newInvalidTypeError(cellA)
+	}
+	out := make(CellList, len(list))
+	copy(out, list)
+	sort.SliceStable(out, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		var cmp int
+		cmp, err = compareCells(out[i], out[j])
+		return cmp < 0
+	})
+	if err != nil {
+		return err
+	}
+	return s.Push(out)
+}
@@ -0,0 +1,56 @@
+package jul
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		desc   string
+		input  string
+		output any
+	}{
+		{desc: "literal", input: "{ 1 *a 1 2 add }", output: CellList{CellInteger(1), CellText("a"), CellInteger(3)}},
+		{desc: "empty literal", input: "{}", output: CellList{}},
+		{desc: "nested literal", input: "{ {1} }", output: CellList{CellList{CellInteger(1)}}},
+		{desc: "length", input: "{ 1 2 3 } length", output: CellInteger(3)},
+		{desc: "get", input: "{ 1 2 3 } 1 get", output: CellInteger(2)},
+		{desc: "append", input: "{ 1 } 2 append", output: CellList{CellInteger(1), CellInteger(2)}},
+		{desc: "slice", input: "{ 1 2 3 } 1 3 slice", output: CellList{CellInteger(2), CellInteger(3)}},
+		{desc: "sort", input: "{ *b *c *a } sort", output: CellList{CellText("a"), CellText("b"), CellText("c")}},
+		{desc: "each", input: "0 { 1 2 3 } [ add ] each", output: CellInteger(6)},
+		{desc: "map", input: "{ 1 2 } [ 2 multiply ] map", output: CellList{CellInteger(2), CellInteger(4)}},
+		{desc: "filter", input: "{ 1 2 3 4 } [ 2 is-modulo ] filter", output: CellList{CellInteger(2), CellInteger(4)}},
+		{desc: "reduce", input: "{ 1 2 3 } 10 [ add ] reduce", output: CellInteger(16)},
+		{desc: "is-equal", input: "{ 1 { *a } } { 1 { *a } } is-equal", output: CellBoolean(true)},
+		{desc: "is-equal with different items", input: "{ 1 2 } { 1 *a } is-equal", output: CellBoolean(false)},
+		{desc: "to-text", input: "{ 1 \"a b\" { true } } to-text", output: CellText(`{1 "a b" {true}}`)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			vm := NewVM()
+			err := vm.Execute(strings.NewReader(test.input))
+			if err != nil {
+				panic(err)
+			}
+			c, err := vm.stack.Pop()
+			if err != nil {
+				panic(err)
+			}
+			if !isEqualCell(c, test.output) {
+				t.Fatalf("got %#v instead of %#v", c, test.output)
+			}
+		})
+	}
+
+	t.Run("fails on index out of range", func(t *testing.T) {
+		vm := NewVM()
+		err := vm.Execute(strings.NewReader("{ 1 } 1 get"))
+		if !errors.Is(err, ErrIndexOutOfRange) {
+			t.Fatalf("got error %v instead of %v", err, ErrIndexOutOfRange)
+		}
+	})
+}
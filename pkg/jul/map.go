@@ -0,0 +1,130 @@
+package jul
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// CellMap is a collection of cells indexed by text keys.
+// Maps are never modified in place, words operating on maps push a new map.
+type CellMap map[string]any
+
+var ErrMissingKey = errors.New("missing key")
+
+// Keys returns the map keys in ascending order.
+func (m CellMap) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m CellMap) clone() CellMap {
+	out := make(CellMap, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// newMapFromPairs returns a map from a list of alternating keys and values.
+func newMapFromPairs(pairs CellList) (CellMap, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("got %d cell(s) instead of key-value pairs", len(pairs))
+	}
+	m := make(CellMap, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		k, ok := pairs[i].(CellText)
+		if !ok {
+			return nil, fmt.Errorf("got key %T instead of text", pairs[i])
+		}
+		m[string(k)] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// popMapAndKey pops a key (B) and the map (A) it refers to.
+func (s *Stack) popMapAndKey() (CellMap, string, error) {
+	cellB, err := s.Pop()
+	if err != nil {
+		return nil, "", err
+	}
+	cellA, err := s.Pop()
+	if err != nil {
+		return nil, "", err
+	}
+	m, ok := cellA.(CellMap)
+	if !ok {
+		return nil, "", newInvalidTypeError(cellA)
+	}
+	k, ok := cellB.(CellText)
+	if !ok {
+		return nil, "", newInvalidTypeError(cellB)
+	}
+	return m, string(k), nil
+}
+
+func (s *Stack) MapGet() error {
+	m, k, err := s.popMapAndKey()
+	if err != nil {
+		return err
+	}
+	v, ok := m[k]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrMissingKey, k)
+	}
+	return s.Push(v)
+}
+
+func (s *Stack) MapHas() error {
+	m, k, err := s.popMapAndKey()
+	if err != nil {
+		return err
+	}
+	_, ok := m[k]
+	return s.Push(CellBoolean(ok))
+}
+
+func (s *Stack) MapDelete() error {
+	m, k, err := s.popMapAndKey()
+	if err != nil {
+		return err
+	}
+	out := m.clone()
+	delete(out, k)
+	return s.Push(out)
+}
+
+func (s *Stack) MapSet() error {
+	cellC, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	m, k, err := s.popMapAndKey()
+	if err != nil {
+		return err
+	}
+	out := m.clone()
+	out[k] = cellC
+	return s.Push(out)
+}
+
+func (s *Stack) MapKeys() error {
+	cellA, err := s.Pop()
+	if err != nil {
+		return err
+	}
+	m, ok := cellA.(CellMap)
+	if !ok {
+		return newInvalidTypeError(cellA)
+	}
+	keys := m.Keys()
+	list := make(CellList, len(keys))
+	for i, k := range keys {
+		list[i] = CellText(k)
+	}
+	return s.Push(list)
+}
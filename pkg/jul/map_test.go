@@ -0,0 +1,70 @@
+package jul
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	tests := []struct {
+		desc   string
+		input  string
+		output any
+	}{
+		{desc: "literal", input: "#{ *name \"Ju\" *party 2 2 add }", output: CellMap{"name": CellText("Ju"), "party": CellInteger(4)}},
+		{desc: "empty literal", input: "#{}", output: CellMap{}},
+		{desc: "nested literal", input: "#{ *a #{ *b {1} } }", output: CellMap{"a": CellMap{"b": CellList{CellInteger(1)}}}},
+		{desc: "map-get", input: "#{ *a 1 } *a map-get", output: CellInteger(1)},
+		{desc: "map-set", input: "#{ *a 1 } *b 2 map-set", output: CellMap{"a": CellInteger(1), "b": CellInteger(2)}},
+		{desc: "map-set overwrites", input: "#{ *a 1 } *a 2 map-set", output: CellMap{"a": CellInteger(2)}},
+		{desc: "map-has", input: "#{ *a 1 } *a map-has", output: CellBoolean(true)},
+		{desc: "map-has missing key", input: "#{ *a 1 } *b map-has", output: CellBoolean(false)},
+		{desc: "map-keys", input: "#{ *b 1 *a 2 } map-keys", output: CellList{CellText("a"), CellText("b")}},
+		{desc: "map-delete", input: "#{ *a 1 *b 2 } *a map-delete", output: CellMap{"b": CellInteger(2)}},
+		{desc: "is-equal", input: "#{ *a {1} } #{ *a {1} } is-equal", output: CellBoolean(true)},
+		{desc: "is-equal with different values", input: "#{ *a 1 } #{ *a 2 } is-equal", output: CellBoolean(false)},
+		{desc: "to-text", input: "#{ *b {1} *a \"x\" } to-text", output: CellText(`#{"a" "x" "b" {1}}`)},
+		{desc: "call starting with map prefix", input: "*#tag [1] define #tag", output: CellInteger(1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			vm := NewVM()
+			err := vm.Execute(strings.NewReader(test.input))
+			if err != nil {
+				panic(err)
+			}
+			c, err := vm.stack.Pop()
+			if err != nil {
+				panic(err)
+			}
+			if !isEqualCell(c, test.output) {
+				t.Fatalf("got %#v instead of %#v", c, test.output)
+			}
+		})
+	}
+
+	t.Run("does not modify the original map", func(t *testing.T) {
+		vm := NewVM()
+		err := vm.Execute(strings.NewReader("#{ *a 1 } dup *b 2 map-set drop"))
+		if err != nil {
+			panic(err)
+		}
+		c, err := vm.stack.Pop()
+		if err != nil {
+			panic(err)
+		}
+		if want := (CellMap{"a": CellInteger(1)}); !isEqualCell(c, want) {
+			t.Fatalf("got %#v instead of %#v", c, want)
+		}
+	})
+
+	t.Run("fails on missing key", func(t *testing.T) {
+		vm := NewVM()
+		err := vm.Execute(strings.NewReader("#{} *a map-get"))
+		if !errors.Is(err, ErrMissingKey) {
+			t.Fatalf("got error %v instead of %v", err, ErrMissingKey)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package jul
+
+import "io"
+
+// Node is an element of the syntax tree.
+//
+// Quotation, list and map nodes hold their parsed body in their children,
+// the raw body is still available in the token value.
+type Node struct {
+	Token
+	Children []*Node
+}
+
+// Parser reads syntax tree nodes from the underlying reader.
+type Parser struct{ src *Source }
+
+func NewParser(r io.Reader) *Parser { return &Parser{src: NewSource(r)} }
+
+// NewNamedParser returns a parser whose node positions refer to the given file name.
+func NewNamedParser(name string, r io.Reader) *Parser {
+	return &Parser{src: NewNamedSource(name, r)}
+}
+
+// Next returns the next top-level node from the source.
+// When EOF is reached, a node of type EOF is returned.
+func (p *Parser) Next() (*Node, error) {
+	tok, err := p.src.Next()
+	if err != nil {
+		return nil, err
+	}
+	return parseToken(tok)
+}
+
+// Parse returns the syntax tree of the given source code.
+// The returned list does not include the final EOF node.
+func Parse(r io.Reader) ([]*Node, error) {
+	p := NewParser(r)
+	var out []*Node
+	for {
+		n, err := p.Next()
+		if err != nil {
+			return out, err
+		}
+		if n.Type == TokenTypeEOF {
+			return out, nil
+		}
+		out = append(out, n)
+	}
+}
+
+// maxNestingDepth is the maximum depth of nested quotations, lists and maps.
+const maxNestingDepth = 1000
+
+func parseToken(tok Token) (*Node, error) {
+	n := &Node{Token: tok}
+	src := &Source{r: newBodyReader(tok.Value), p: tok.Position}
+	switch tok.Type {
+	default:
+		return n, nil
+	case TokenTypeQuotation, TokenTypeList:
+		src.p.Column++
+	case TokenTypeMap:
+		src.p.Column += 2
+	}
+
+	// Parse body, positions are relative to the character following the opening mark.
+	return n, parseBody(src, n, 1)
+}
+
+// parseBody parses the children of a node from a source reading its body.
+//
+// Nested bodies are parsed in place, in a single pass:
+// reading them again for each level would take quadratic time in the nesting depth.
+func parseBody(src *Source, n *Node, depth int) error {
+	r := src.r.(*bodyReader)
+	end := r.end
+	for {
+		child, err := src.Next()
+		if err != nil {
+			return err
+		}
+		if child.Type == TokenTypeEOF {
+			return nil
+		}
+		childNode := &Node{Token: child}
+		n.Children = append(n.Children, childNode)
+		switch child.Type {
+		case TokenTypeQuotation, TokenTypeList, TokenTypeMap:
+		default:
+			continue
+		}
+		if depth >= maxNestingDepth {
+			return syntaxError{Position: child.Position, Message: "too deeply nested"}
+		}
+
+		// Parse the child body, then skip its closing mark
+		r.end = r.off + len(child.Value)
+		err = parseBody(src, childNode, depth+1)
+		r.end = end
+		if err != nil {
+			return err
+		}
+		_, err = src.read()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// bodyReader reads a body up to the end offset, which is changed while nested bodies are parsed.
+type bodyReader struct {
+	text     string
+	off, end int
+	ends     map[int]int // Offset of the closing mark of the bodies starting at each offset
+}
+
+func newBodyReader(text string) *bodyReader {
+	return &bodyReader{text: text, end: len(text), ends: map[int]int{}}
+}
+
+// index finds the end of the body starting at the given offset, and the end of the bodies nested in it.
+// Bodies are indexed once, unless a nested body starts in what was read as a literal text or comment.
+func (r *bodyReader) index(start int, markEnd byte) {
+	var quotations, lists []int
+	if markEnd == MarkAnonymousFunctionEnd {
+		quotations = append(quotations, start)
+	} else {
+		lists = append(lists, start)
+	}
+	s := bodyScanner{isTokenStart: true}
+	for i := start; i < r.end; i++ {
+		c := r.text[i]
+		if !s.scan(c) {
+			continue
+		}
+		switch c {
+		case MarkAnonymousFunctionStart:
+			quotations = append(quotations, i+1)
+		case MarkListStart:
+			lists = append(lists, i+1)
+		case MarkAnonymousFunctionEnd:
+			if len(quotations) > 0 {
+				r.ends[quotations[len(quotations)-1]] = i
+				quotations = quotations[:len(quotations)-1]
+			}
+		case MarkListEnd:
+			if len(lists) > 0 {
+				r.ends[lists[len(lists)-1]] = i
+				lists = lists[:len(lists)-1]
+			}
+		}
+		if _, ok := r.ends[start]; ok {
+			return
+		}
+	}
+}
+
+func (r *bodyReader) Read(b []byte) (int, error) {
+	if r.off >= r.end {
+		return 0, io.EOF
+	}
+	n := copy(b, r.text[r.off:r.end])
+	r.off += n
+	return n, nil
+}
@@ -0,0 +1,94 @@
+package jul
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		desc   string
+		input  string
+		output []*Node
+	}{
+		{
+			desc:  "flat",
+			input: "1 dup",
+			output: []*Node{
+				{Token: Token{Position: Position{Line: 1, Column: 1}, Type: TokenTypeFunctionCall, Value: "1"}},
+				{Token: Token{Position: Position{Line: 1, Column: 3}, Type: TokenTypeFunctionCall, Value: "dup"}},
+			},
+		},
+		{
+			desc:  "nested quotations",
+			input: "[dup [write]]",
+			output: []*Node{
+				{
+					Token: Token{Position: Position{Line: 1, Column: 1}, Type: TokenTypeQuotation, Value: "dup [write]"},
+					Children: []*Node{
+						{Token: Token{Position: Position{Line: 1, Column: 2}, Type: TokenTypeFunctionCall, Value: "dup"}},
+						{
+							Token: Token{Position: Position{Line: 1, Column: 6}, Type: TokenTypeQuotation, Value: "write"},
+							Children: []*Node{
+								{Token: Token{Position: Position{Line: 1, Column: 7}, Type: TokenTypeFunctionCall, Value: "write"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc:  "multi-line quotation",
+			input: "*greet [\n\t\"Hello\" (comment) write\n]",
+			output: []*Node{
+				{Token: Token{Position: Position{Line: 1, Column: 1}, Type: TokenTypeLiteralTextWord, Value: "greet"}},
+				{
+					Token: Token{Position: Position{Line: 1, Column: 8}, Type: TokenTypeQuotation, Value: "\n\t\"Hello\" (comment) write\n"},
+					Children: []*Node{
+						{Token: Token{Position: Position{Line: 2, Column: 2}, Type: TokenTypeLiteralText, Value: "Hello"}},
+						{Token: Token{Position: Position{Line: 2, Column: 10}, Type: TokenTypeComment, Value: "comment"}},
+						{Token: Token{Position: Position{Line: 2, Column: 20}, Type: TokenTypeFunctionCall, Value: "write"}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			nodes, err := Parse(strings.NewReader(test.input))
+			if err != nil {
+				panic(err)
+			}
+			if !reflect.DeepEqual(nodes, test.output) {
+				t.Fatalf("got %+v instead of %+v", nodes, test.output)
+			}
+		})
+	}
+}
+
+func TestParseNesting(t *testing.T) {
+	t.Run("parses nested bodies", func(t *testing.T) {
+		code := strings.Repeat("[{", maxNestingDepth/2) + strings.Repeat("}]", maxNestingDepth/2)
+		nodes, err := Parse(strings.NewReader(code))
+		if err != nil {
+			panic(err)
+		}
+		depth := 0
+		for n := nodes[0]; len(n.Children) > 0; n = n.Children[0] {
+			depth++
+		}
+		if depth != maxNestingDepth-1 {
+			t.Fatalf("got depth %d instead of %d", depth, maxNestingDepth-1)
+		}
+	})
+
+	t.Run("rejects too deeply nested bodies", func(t *testing.T) {
+		code := strings.Repeat("[", 100_000) + strings.Repeat("]", 100_000)
+		_, err := Parse(strings.NewReader(code))
+		if err == nil || !strings.Contains(err.Error(), "too deeply nested") {
+			t.Fatalf("got error %v", err)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package jul
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Capability is a sensitive feature that a word needs, see Definition.Capability.
+type Capability string
+
+const (
+	CapabilityNetwork Capability = "network" // Send data to the server
+	CapabilityStorage Capability = "storage" // Persist data on the client
+	CapabilityTimers  Capability = "timers"  // Read the clock and pause execution
+	CapabilityInstall Capability = "install" // Install scripts executed periodically by the client
+)
+
+var capabilityDescriptions = map[Capability]string{
+	CapabilityNetwork: "the network (send data to the server)",
+	CapabilityStorage: "the storage (save data on this device)",
+	CapabilityTimers:  "timers (read the clock and wait)",
+	CapabilityInstall: "background scripts (run code periodically, even after disconnecting)",
+}
+
+// Description returns a description of the capability for the user.
+func (c Capability) Description() string {
+	if d, ok := capabilityDescriptions[c]; ok {
+		return d
+	}
+	return string(c)
+}
+
+// ErrPermissionDenied is returned when a word needs a capability that was not granted.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// PermissionPrompter is implemented by UIs that can ask the user to grant a capability.
+type PermissionPrompter interface {
+	AskPermission(origin string, c Capability) (bool, error)
+}
+
+// WithPermissions sets the capabilities granted to the code executed by the VM.
+// By default, all capabilities are granted (as for local scripts).
+func WithPermissions(p *Permissions) Option { return func(vm *VM) { vm.permissions = p } }
+
+// Permissions holds the capabilities granted to a server origin.
+//
+// The first time a capability is needed, the user is asked through the VM UI
+// (if it implements PermissionPrompter) and the answer is saved in the store.
+// Capabilities are denied if the UI can't ask the user.
+type Permissions struct {
+	Origin string
+
+	mu      sync.Mutex
+	store   Store
+	granted map[Capability]bool // Decisions already loaded from the store
+}
+
+// NewPermissions returns the permissions of the given origin, decisions are persisted in the given store
+// (or kept in memory if nil).
+func NewPermissions(origin string, store Store) *Permissions {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Permissions{Origin: origin, store: store, granted: map[Capability]bool{}}
+}
+
+// Grant allows the use of the given capability without asking the user.
+func (p *Permissions) Grant(c Capability) error { return p.set(c, true) }
+
+// Deny forbids the use of the given capability without asking the user.
+func (p *Permissions) Deny(c Capability) error { return p.set(c, false) }
+
+// Reset forgets the decision for the given capability, so the user is asked again.
+func (p *Permissions) Reset(c Capability) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.granted, c)
+	return p.store.Delete(string(c))
+}
+
+func (p *Permissions) set(c Capability, granted bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.save(c, granted)
+}
+
+func (p *Permissions) save(c Capability, granted bool) error {
+	b, err := json.Marshal(granted)
+	if err != nil {
+		return err
+	}
+	err = p.store.Set(string(c), b)
+	if err != nil {
+		return err
+	}
+	p.granted[c] = granted
+	return nil
+}
+
+// Check returns nil if the capability is granted, the user is asked with the given UI if needed.
+func (p *Permissions) Check(ui UI, c Capability) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Load previous decision
+	granted, ok := p.granted[c]
+	if !ok {
+		b, err := p.store.Get(string(c))
+		switch {
+		case err == nil:
+			err = json.Unmarshal(b, &granted)
+			if err != nil {
+				return fmt.Errorf("decode permission %q: %w", c, err)
+			}
+			p.granted[c], ok = granted, true
+		case !errors.Is(err, ErrMissingKey):
+			return err
+		}
+	}
+
+	// Ask user the first time
+	if !ok {
+		prompter, canAsk := ui.(PermissionPrompter)
+		if !canAsk {
+			return fmt.Errorf("%w: %q capability was not granted", ErrPermissionDenied, c)
+		}
+		var err error
+		granted, err = prompter.AskPermission(p.Origin, c)
+		if err != nil {
+			return fmt.Errorf("ask permission: %w", err)
+		}
+		err = p.save(c, granted)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !granted {
+		return fmt.Errorf("%w: %q capability was denied", ErrPermissionDenied, c)
+	}
+	return nil
+}
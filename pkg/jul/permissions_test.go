@@ -0,0 +1,64 @@
+package jul
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	t.Run("denies capabilities if the UI can't ask", func(t *testing.T) {
+		vm := NewVM(WithUI(NewScriptedUI()), WithPermissions(NewPermissions("example.com", nil)))
+		err := vm.Execute(strings.NewReader(`*key 1 store-set`))
+		if !errors.Is(err, ErrPermissionDenied) {
+			t.Fatalf("got error %v instead of %v", err, ErrPermissionDenied)
+		}
+	})
+
+	t.Run("allows granted capabilities", func(t *testing.T) {
+		p := NewPermissions("example.com", nil)
+		err := p.Grant(CapabilityStorage)
+		if err != nil {
+			panic(err)
+		}
+		vm := NewVM(WithUI(NewScriptedUI()), WithPermissions(p))
+		err = vm.Execute(strings.NewReader(`*key 1 store-set`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = vm.Execute(strings.NewReader(`now`))
+		if !errors.Is(err, ErrPermissionDenied) {
+			t.Fatalf("got error %v instead of %v", err, ErrPermissionDenied)
+		}
+	})
+
+	t.Run("asks the user the first time", func(t *testing.T) {
+		store := NewMemoryStore()
+		out := &strings.Builder{}
+		ui := NewDefaultUI(strings.NewReader("y\nn\n"), out)
+		vm := NewVM(WithUI(ui), WithPermissions(NewPermissions("example.com", store)))
+		err := vm.Execute(strings.NewReader(`*key 1 store-set *key store-get`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Count(out.String(), "Allow example.com") != 1 {
+			t.Fatalf("got output %q", out)
+		}
+		err = vm.Execute(strings.NewReader(`now`))
+		if !errors.Is(err, ErrPermissionDenied) {
+			t.Fatalf("got error %v instead of %v", err, ErrPermissionDenied)
+		}
+
+		// Decisions are saved in the store
+		vm = NewVM(WithUI(NewDefaultUI(strings.NewReader(""), io.Discard)), WithPermissions(NewPermissions("example.com", store)))
+		err = vm.Execute(strings.NewReader(`*key store-has`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = vm.Execute(strings.NewReader(`now`))
+		if !errors.Is(err, ErrPermissionDenied) {
+			t.Fatalf("got error %v instead of %v", err, ErrPermissionDenied)
+		}
+	})
+}
@@ -43,4 +43,16 @@ func TestPrelude(t *testing.T) {
 			t.Fatalf("got random number %d instead of %d", result, 1)
 		}
 	})
+
+	t.Run("passes Jul tests", func(t *testing.T) {
+		results, err := RunTests("prelude_test.ju")
+		if err != nil {
+			panic(err)
+		}
+		for _, res := range results {
+			if res.Err != nil {
+				t.Errorf("%s (%s): %s", res.Name, res.Position, res.Err)
+			}
+		}
+	})
 }
@@ -0,0 +1,150 @@
+package jul
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// QuoteText returns a literal text token that evaluates to the given text.
+// It can be used to safely insert any text value in Jul code.
+func QuoteText(s string) string {
+	var b strings.Builder
+	b.WriteByte(MarkLiteralTextQuote)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case MarkLiteralTextQuote, '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case 0:
+			b.WriteString(`\0`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(MarkLiteralTextQuote)
+	return b.String()
+}
+
+// Literal returns Jul code that pushes the given value on the stack.
+//
+// Supported values are text, integers, booleans, lists and maps
+// (as cells or as their Go equivalent: string, int, bool, []any and map[string]any).
+func Literal(v any) (string, error) {
+	switch v := v.(type) {
+	default:
+		return "", fmt.Errorf("can't write %T as a literal", v)
+	case string:
+		return QuoteText(v), nil
+	case CellText:
+		return QuoteText(string(v)), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case CellInteger:
+		return strconv.Itoa(int(v)), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case CellBoolean:
+		return strconv.FormatBool(bool(v)), nil
+	case []any:
+		return Literal(CellList(v))
+	case CellList:
+		items := make([]string, len(v))
+		for i, item := range v {
+			code, err := Literal(item)
+			if err != nil {
+				return "", fmt.Errorf("list item %d: %w", i, err)
+			}
+			items[i] = code
+		}
+		return string(MarkListStart) + strings.Join(items, " ") + string(MarkListEnd), nil
+	case map[string]any:
+		return Literal(CellMap(v))
+	case CellMap:
+		items := make([]string, 0, 2*len(v))
+		for _, k := range v.Keys() {
+			code, err := Literal(v[k])
+			if err != nil {
+				return "", fmt.Errorf("map key %q: %w", k, err)
+			}
+			items = append(items, QuoteText(k), code)
+		}
+		return string(MarkMapPrefix) + string(MarkListStart) + strings.Join(items, " ") + string(MarkListEnd), nil
+	}
+}
+
+// Format replaces placeholders in the given code by literals pushing the corresponding argument.
+// Values are always inserted as literal cells and can't be interpreted as code.
+//
+// Placeholders are typed:
+//   - %s for text (string or CellText)
+//   - %d for integers (int, int64 or CellInteger)
+//   - %t for booleans (bool or CellBoolean)
+//   - %v for any value supported by Literal
+//   - %% for a literal percent sign
+func Format(code string, args ...any) (string, error) {
+	var b strings.Builder
+	argi := 0
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(code) {
+			return "", fmt.Errorf("missing verb after %% at end of code")
+		}
+		verb := code[i]
+		if verb == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		if argi >= len(args) {
+			return "", fmt.Errorf("missing argument for %%%c", verb)
+		}
+		arg := args[argi]
+		argi++
+
+		ok := false
+		switch verb {
+		default:
+			return "", fmt.Errorf("unknown verb %%%c", verb)
+		case 's':
+			switch arg.(type) {
+			case string, CellText:
+				ok = true
+			}
+		case 'd':
+			switch arg.(type) {
+			case int, int64, CellInteger:
+				ok = true
+			}
+		case 't':
+			switch arg.(type) {
+			case bool, CellBoolean:
+				ok = true
+			}
+		case 'v':
+			ok = true
+		}
+		if !ok {
+			return "", fmt.Errorf("got %T for %%%c (argument %d)", arg, verb, argi)
+		}
+		lit, err := Literal(arg)
+		if err != nil {
+			return "", fmt.Errorf("argument %d: %w", argi, err)
+		}
+		b.WriteString(lit)
+	}
+	if argi != len(args) {
+		return "", fmt.Errorf("got %d argument(s) for %d placeholder(s)", len(args), argi)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,74 @@
+package jul
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuoteText(t *testing.T) {
+	inputs := []string{
+		"",
+		"Hello world!",
+		`"quoted"`,
+		`back\slash\`,
+		"new\nline\tand tab",
+		"null\x00byte",
+		`"] drop "injected" write [`,
+		`") (`,
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			// Values must round-trip both at top-level and in nested bodies
+			for _, code := range []string{
+				QuoteText(input),
+				"[ " + QuoteText(input) + " ] do",
+				"{ [ (" + QuoteText(input) + ") " + QuoteText(input) + " ] do } 0 get",
+			} {
+				vm := NewVM()
+				err := vm.Execute(strings.NewReader(code))
+				if err != nil {
+					t.Fatalf("%s: %s", code, err)
+				}
+				if vm.stack.Len() != 1 {
+					t.Fatalf("%s: got %d cells instead of 1", code, vm.stack.Len())
+				}
+				c, _ := vm.stack.Pop()
+				if c != CellText(input) {
+					t.Fatalf("%s: got %#v instead of %#v", code, c, input)
+				}
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	t.Run("inserts values as literals", func(t *testing.T) {
+		code, err := Format(`%s %d %t %v "100%%"`, `"] write [`, 42, true, map[string]any{"a": []any{1, "b"}})
+		if err != nil {
+			panic(err)
+		}
+		want := `"\"] write [" 42 true #{"a" {1 "b"}} "100%"`
+		if code != want {
+			t.Fatalf("got %s instead of %s", code, want)
+		}
+	})
+
+	for _, test := range []struct {
+		desc string
+		code string
+		args []any
+	}{
+		{desc: "mismatched type", code: "%d", args: []any{"1"}},
+		{desc: "missing argument", code: "%s %s", args: []any{"a"}},
+		{desc: "extra argument", code: "%s", args: []any{"a", "b"}},
+		{desc: "unknown verb", code: "%x", args: []any{1}},
+		{desc: "unsupported value", code: "%v", args: []any{1.5}},
+	} {
+		t.Run("fails on "+test.desc, func(t *testing.T) {
+			_, err := Format(test.code, test.args...)
+			if err == nil {
+				t.Fatal("got nil error")
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package jul
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	replPrompt             = "> "
+	replContinuationPrompt = "... "
+)
+
+// REPL reads code line by line and executes it, printing the stack after each entry.
+// Errors are printed and don't end the session.
+//
+// Entries are read until all quotations, lists, maps, comments and texts are closed,
+// so they can span multiple lines. Lines starting with a dot are meta-commands (see ".help").
+//
+// Previous entries are listed with ".history" and executed again with "!!" and "!N".
+// Read from a LineEditor for line editing and history navigation with the arrow keys (as RunCLI does).
+type REPL struct {
+	vm      *VM
+	r       *bufio.Reader
+	w       io.Writer
+	history []string
+	histf   *os.File // Optional file where entries are appended
+}
+
+// NewREPL returns a REPL reading from r and writing to w.
+// The VM UI should read from the same reader so that user input and code are read in order.
+func NewREPL(vm *VM, r *bufio.Reader, w io.Writer) *REPL {
+	return &REPL{vm: vm, r: r, w: w}
+}
+
+// WithHistoryFile loads previous entries from the given file and appends new entries to it.
+// Each line of the file is an entry quoted as a Go string, so that entries can span multiple lines.
+func (repl *REPL) WithHistoryFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		entry, err := strconv.Unquote(line)
+		if err == nil {
+			repl.history = append(repl.history, entry)
+		}
+	}
+	repl.histf, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	return err
+}
+
+// Run executes entries until the reader reaches EOF.
+func (repl *REPL) Run() error {
+	if repl.histf != nil {
+		defer repl.histf.Close()
+	}
+	fmt.Fprintln(repl.w, `Jul REPL, type ".help" for help.`)
+	for {
+		entry, err := repl.readEntry()
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(repl.w)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		// Re-run entry from history
+		if strings.HasPrefix(entry, "!") {
+			entry, err = repl.fromHistory(entry)
+			if err != nil {
+				fmt.Fprintln(repl.w, err)
+				continue
+			}
+			fmt.Fprintln(repl.w, entry)
+		}
+		repl.addToHistory(entry)
+
+		if strings.HasPrefix(entry, ".") {
+			repl.runCommand(entry)
+			continue
+		}
+		err = repl.vm.ExecuteNamed("repl", strings.NewReader(entry))
+		if err != nil {
+			fmt.Fprintln(repl.w, err)
+		}
+		repl.printStack()
+	}
+}
+
+// readEntry reads lines until the entry is complete.
+func (repl *REPL) readEntry() (string, error) {
+	var entry string
+	prompt := replPrompt
+	for {
+		fmt.Fprint(repl.w, prompt)
+		line, err := repl.r.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			return "", err
+		}
+		entry += line
+		if strings.HasPrefix(entry, ".") || strings.HasPrefix(entry, "!") {
+			return strings.TrimSpace(entry), nil
+		}
+		_, err = Parse(strings.NewReader(entry))
+		if !errors.Is(err, ErrUnexpectedEOF) {
+			return strings.TrimSuffix(entry, "\n"), nil
+		}
+		prompt = replContinuationPrompt
+	}
+}
+
+func (repl *REPL) addToHistory(entry string) {
+	repl.history = append(repl.history, entry)
+	if repl.histf != nil {
+		fmt.Fprintln(repl.histf, strconv.Quote(entry))
+	}
+}
+
+// fromHistory returns the entry referred to by "!!" (last entry) or "!N" (Nth entry).
+func (repl *REPL) fromHistory(ref string) (string, error) {
+	if ref == "!!" {
+		if len(repl.history) == 0 {
+			return "", errors.New("history is empty")
+		}
+		return repl.history[len(repl.history)-1], nil
+	}
+	n, err := strconv.Atoi(ref[1:])
+	if err != nil || n < 1 || n > len(repl.history) {
+		return "", fmt.Errorf("no history entry %q", ref)
+	}
+	return repl.history[n-1], nil
+}
+
+func (repl *REPL) runCommand(entry string) {
+	name, arg, _ := strings.Cut(entry, " ")
+	switch name {
+	default:
+		fmt.Fprintf(repl.w, "unknown command %q, type \".help\" for help\n", name)
+	case ".help":
+		fmt.Fprint(repl.w, ""+
+			".words        list defined words\n"+
+			".stack        print the stack\n"+
+			".clear        remove all cells from the stack\n"+
+			".load <file>  execute a file\n"+
+			".history      list previous entries\n"+
+			"!!            execute the last entry again\n"+
+			"!<n>          execute entry n again\n",
+		)
+	case ".words":
+		fmt.Fprintln(repl.w, strings.Join(repl.vm.dictionary.Words(), " "))
+	case ".stack":
+		repl.printStack()
+	case ".clear":
+		repl.vm.stack.Clear()
+		repl.printStack()
+	case ".load":
+		path := strings.TrimSpace(arg)
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(repl.w, err)
+			return
+		}
+		defer f.Close()
+		err = repl.vm.ExecuteNamed(path, f)
+		if err != nil {
+			fmt.Fprintln(repl.w, err)
+		}
+		repl.printStack()
+	case ".history":
+		for i, entry := range repl.history {
+			fmt.Fprintf(repl.w, "%4d  %s\n", i+1, strings.ReplaceAll(entry, "\n", "\n      "))
+		}
+	}
+}
+
+// printStack prints the number of cells followed by the cells (top cell last).
+func (repl *REPL) printStack() {
+	cells := repl.vm.stack.Cells()
+	items := make([]string, 0, len(cells)+1)
+	items = append(items, "<"+strconv.Itoa(len(cells))+">")
+	for _, c := range cells {
+		v, err := formatCell(c, true)
+		if err != nil {
+			v = fmt.Sprintf("%T", c)
+		}
+		items = append(items, v)
+	}
+	fmt.Fprintln(repl.w, strings.Join(items, " "))
+}
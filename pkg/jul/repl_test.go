@@ -0,0 +1,85 @@
+package jul
+
+import (
+	"bufio"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	run := func(input string) string {
+		r := bufio.NewReader(strings.NewReader(input))
+		out := &strings.Builder{}
+		vm := NewVM(WithUI(NewDefaultUI(r, out)))
+		err := NewREPL(vm, r, out).Run()
+		if err != nil {
+			panic(err)
+		}
+		return out.String()
+	}
+
+	t.Run("prints stack after each entry", func(t *testing.T) {
+		out := run("1 2\n*a\n")
+		if !strings.Contains(out, "> <2> 1 2\n") || !strings.Contains(out, "> <3> 1 2 \"a\"\n") {
+			t.Fatalf("got output %q", out)
+		}
+	})
+
+	t.Run("reads multi-line entries", func(t *testing.T) {
+		out := run("[\n1\n] do (a\ncomment)\n")
+		if !strings.Contains(out, "> ... ... ... <1> 1\n") {
+			t.Fatalf("got output %q", out)
+		}
+	})
+
+	t.Run("keeps running after errors", func(t *testing.T) {
+		out := run("unknown-word\n1\n")
+		if !strings.Contains(out, `unknown word "unknown-word"`) || !strings.Contains(out, "<1> 1\n") {
+			t.Fatalf("got output %q", out)
+		}
+	})
+
+	t.Run("reads user input from the same reader", func(t *testing.T) {
+		out := run("read\nhello\n")
+		if !strings.Contains(out, "<1> \"hello\"\n") {
+			t.Fatalf("got output %q", out)
+		}
+	})
+
+	t.Run("supports meta-commands and history", func(t *testing.T) {
+		out := run("1\n.clear\n!1\n.history\n.words\n")
+		if !strings.Contains(out, "<0>\n") || !strings.Contains(out, "   4  .history\n") || !strings.Contains(out, " random-between") {
+			t.Fatalf("got output %q", out)
+		}
+		if strings.Count(out, "<1> 1\n") != 2 {
+			t.Fatalf("history entry not executed: %q", out)
+		}
+	})
+}
+
+func TestREPLHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	entries := []string{`"a\nb" write`, "[\n\t1\n] do"}
+	open := func(input string) *REPL {
+		r := bufio.NewReader(strings.NewReader(input))
+		out := &strings.Builder{}
+		repl := NewREPL(NewVM(WithUI(NewDefaultUI(r, out))), r, out)
+		err := repl.WithHistoryFile(path)
+		if err != nil {
+			panic(err)
+		}
+		return repl
+	}
+	err := open(strings.Join(entries, "\n") + "\n").Run()
+	if err != nil {
+		panic(err)
+	}
+
+	// Entries are loaded unchanged by the next session
+	repl := open("")
+	if !reflect.DeepEqual(repl.history, entries) {
+		t.Fatalf("got history %q instead of %q", repl.history, entries)
+	}
+}
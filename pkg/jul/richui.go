@@ -0,0 +1,197 @@
+package jul
+
+import (
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InputKind is the type of value asked to the user with RichUI.Input.
+type InputKind string
+
+const (
+	InputNumber InputKind = "number"
+	InputDate   InputKind = "date" // Formatted as YYYY-MM-DD
+	InputEmail  InputKind = "email"
+)
+
+// DateLayout is the layout of dates typed by the user.
+const DateLayout = "2006-01-02"
+
+// RichUI is a UI that can ask the user to pick an option or to type a value of a given kind,
+// so that scripts don't need to parse free text.
+//
+// Words like "ask-choice" use the UI methods if it implements RichUI,
+// otherwise they fall back to numbered menus built with Write and Read (see TextUI).
+type RichUI interface {
+	UI
+	Choose(prompt string, options []string) (int, error) // Returns the index of the chosen option
+	Confirm(prompt string) (bool, error)
+	Input(prompt string, kind InputKind) (string, error) // Returns a value that passes ValidateInput
+}
+
+// ValidateInput returns an error if the text is not a valid value of the given kind.
+func ValidateInput(kind InputKind, s string) error {
+	switch kind {
+	default:
+		return fmt.Errorf("unknown input kind %q", kind)
+	case InputNumber:
+		_, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+	case InputDate:
+		_, err := time.Parse(DateLayout, s)
+		if err != nil {
+			return fmt.Errorf("%q is not a date (YYYY-MM-DD)", s)
+		}
+	case InputEmail:
+		addr, err := mail.ParseAddress(s)
+		if err != nil || addr.Address != s {
+			return fmt.Errorf("%q is not an email address", s)
+		}
+	}
+	return nil
+}
+
+// richUI returns the UI as a RichUI, using text menus if it doesn't implement it.
+func richUI(ui UI) RichUI {
+	if rich, ok := ui.(RichUI); ok {
+		return rich
+	}
+	return TextUI{ui}
+}
+
+// TextUI implements RichUI with numbered menus and validation loops,
+// for UIs that can only write and read text (like DefaultUI).
+type TextUI struct{ UI }
+
+func (ui TextUI) Choose(prompt string, options []string) (int, error) {
+	if len(options) == 0 {
+		return 0, fmt.Errorf("no options to choose from")
+	}
+	menu := prompt + "\n"
+	for i, option := range options {
+		menu += fmt.Sprintf("  %d. %s\n", i+1, option)
+	}
+	err := ui.Write(menu)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		err = ui.Write("> ")
+		if err != nil {
+			return 0, err
+		}
+		answer, err := ui.Read()
+		if err != nil {
+			return 0, err
+		}
+		answer = strings.TrimSpace(answer)
+
+		// Accept option number or option text
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		for i, option := range options {
+			if strings.EqualFold(answer, option) {
+				return i, nil
+			}
+		}
+		err = ui.Write(fmt.Sprintf("Please type a number between 1 and %d.\n", len(options)))
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (ui TextUI) Confirm(prompt string) (bool, error) {
+	for {
+		err := ui.Write(prompt + " [y/n] ")
+		if err != nil {
+			return false, err
+		}
+		answer, err := ui.Read()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		err = ui.Write("Please answer yes or no.\n")
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+func (ui TextUI) Input(prompt string, kind InputKind) (string, error) {
+	if kind == InputDate {
+		prompt += " (YYYY-MM-DD)"
+	}
+	for {
+		err := ui.Write(prompt + " ")
+		if err != nil {
+			return "", err
+		}
+		answer, err := ui.Read()
+		if err != nil {
+			return "", err
+		}
+		answer = strings.TrimSpace(answer)
+		err = ValidateInput(kind, answer)
+		if err == nil {
+			return answer, nil
+		}
+		err = ui.Write(fmt.Sprintf("Invalid %s: %s.\n", kind, err))
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+func (ui *DefaultUI) Choose(prompt string, options []string) (int, error) {
+	return TextUI{ui}.Choose(prompt, options)
+}
+
+func (ui *DefaultUI) Confirm(prompt string) (bool, error) { return TextUI{ui}.Confirm(prompt) }
+
+func (ui *DefaultUI) Input(prompt string, kind InputKind) (string, error) {
+	return TextUI{ui}.Input(prompt, kind)
+}
+
+// popPrompt pops the prompt text of the "ask-..." words.
+func popPrompt(vm *VM) (string, error) {
+	cellA, err := vm.stack.Pop()
+	if err != nil {
+		return "", fmt.Errorf("pop (A) prompt (text): %w", err)
+	}
+	prompt, ok := cellA.(CellText)
+	if !ok {
+		return "", fmt.Errorf("got (A) %T instead of text", cellA)
+	}
+	return string(prompt), nil
+}
+
+// askInput asks for a value of the given kind, the answer is validated again as the UI may be remote.
+func askInput(vm *VM, kind InputKind) (string, error) {
+	prompt, err := popPrompt(vm)
+	if err != nil {
+		return "", err
+	}
+	answer, err := richUI(vm.ui).Input(prompt, kind)
+	if err != nil {
+		return "", err
+	}
+	err = ValidateInput(kind, answer)
+	if err != nil {
+		return "", err
+	}
+	vm.trackInput(answer)
+	return answer, nil
+}
@@ -0,0 +1,135 @@
+package jul
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAskWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		steps []TranscriptStep
+		want  any
+	}{
+		{
+			name: "choice by number",
+			code: `"Size?" {*Small *Large} ask-choice`,
+			steps: []TranscriptStep{
+				TranscriptOutput("Size?\n  1. Small\n  2. Large\n>"),
+				TranscriptInput("3"),
+				TranscriptOutput("Please type a number between 1 and 2.\n>"),
+				TranscriptInput("2"),
+			},
+			want: CellText("Large"),
+		},
+		{
+			name:  "choice by text",
+			code:  `"Size?" {*Small *Large} ask-choice`,
+			steps: []TranscriptStep{TranscriptOutputMatch(`(?s).*`), TranscriptInput("small")},
+			want:  CellText("Small"),
+		},
+		{
+			name: "confirm",
+			code: `"Continue?" ask-confirm`,
+			steps: []TranscriptStep{
+				TranscriptOutput("Continue? [y/n]"),
+				TranscriptInput("maybe"),
+				TranscriptOutput("Please answer yes or no.\nContinue? [y/n]"),
+				TranscriptInput("n"),
+			},
+			want: CellBoolean(false),
+		},
+		{
+			name: "integer",
+			code: `"Age?" ask-number`,
+			steps: []TranscriptStep{
+				TranscriptOutput("Age?"),
+				TranscriptInput("old"),
+				TranscriptOutput("Invalid number: \"old\" is not a number.\nAge?"),
+				TranscriptInput("42"),
+			},
+			want: CellInteger(42),
+		},
+		{
+			name:  "float",
+			code:  `"Price?" ask-number`,
+			steps: []TranscriptStep{TranscriptOutput("Price?"), TranscriptInput("9.5")},
+			want:  CellFloat(9.5),
+		},
+		{
+			name:  "date",
+			code:  `"Birthday?" ask-date`,
+			steps: []TranscriptStep{TranscriptOutput("Birthday? (YYYY-MM-DD)"), TranscriptInput("2000-01-31")},
+			want:  CellTime(time.Date(2000, 1, 31, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			name: "email",
+			code: `"Email?" ask-email`,
+			steps: []TranscriptStep{
+				TranscriptOutput("Email?"),
+				TranscriptInput("ju"),
+				TranscriptOutputMatch(`Invalid email: .*\nEmail\?`),
+				TranscriptInput("ju@example.com"),
+			},
+			want: CellText("ju@example.com"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ui := NewScriptedUI(test.steps...)
+			vm := NewVM(WithUI(ui))
+			err := vm.Execute(strings.NewReader(test.code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ui.Done()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := vm.stack.Pop()
+			if err != nil {
+				panic(err)
+			}
+			if !isEqualCell(got, test.want) {
+				t.Fatalf("got %v instead of %v", got, test.want)
+			}
+		})
+	}
+}
+
+// fakeRichUI answers all questions with the same values.
+type fakeRichUI struct {
+	*ScriptedUI
+	choice int
+	input  string
+}
+
+func (ui fakeRichUI) Choose(string, []string) (int, error)    { return ui.choice, nil }
+func (ui fakeRichUI) Confirm(string) (bool, error)            { return true, nil }
+func (ui fakeRichUI) Input(string, InputKind) (string, error) { return ui.input, nil }
+
+func TestRichUI(t *testing.T) {
+	t.Run("uses UI methods", func(t *testing.T) {
+		vm := NewVM(WithUI(fakeRichUI{ScriptedUI: NewScriptedUI(), choice: 1, input: "7"}))
+		err := vm.Execute(strings.NewReader(`"?" {*a *b} ask-choice "?" ask-confirm "?" ask-number`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []any{CellText("b"), CellBoolean(true), CellInteger(7)}
+		if got := vm.stack.Cells(); !isEqualCell(CellList(got), CellList(want)) {
+			t.Fatalf("got %v instead of %v", got, want)
+		}
+	})
+
+	t.Run("validates answers", func(t *testing.T) {
+		vm := NewVM(WithUI(fakeRichUI{ScriptedUI: NewScriptedUI(), choice: 5, input: "seven"}))
+		for _, code := range []string{`"?" ask-number`, `"?" {*a *b} ask-choice`} {
+			err := vm.Execute(strings.NewReader(code))
+			if err == nil {
+				t.Fatalf("got no error for %q", code)
+			}
+		}
+	})
+}
@@ -5,10 +5,22 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 )
 
-type Stack struct{ cells []any }
+type Stack struct {
+	cells     []any
+	maxMemory int // Memory budget in bytes (0 means no limit)
+	memory    int // Estimated memory used by the cells (only tracked if there's a budget)
+
+	// Provenance of the cells, to tell users which of their inputs a script uploads:
+	// inputs holds the texts typed by the user that each cell was derived from,
+	// frames holds the inputs of the cells popped by each instruction being executed (innermost last),
+	// cells pushed by an instruction are derived from all the cells it popped.
+	inputs [][]string
+	frames [][]string
+}
 
 func NewStack(capacity int) *Stack {
 	if capacity <= 0 {
@@ -23,15 +35,28 @@ var (
 )
 
 type (
-	CellBoolean   bool
-	CellInteger   int
-	CellFloat     float64
-	CellText      string
-	CellQuotation string
-	CellTime      time.Time
+	CellBoolean bool
+	CellInteger int
+	CellFloat   float64
+	CellText    string
+	CellTime    time.Time
 )
 
+// CellQuotation is an anonymous function, it holds both its source code and compiled instructions.
+type CellQuotation struct {
+	Source string
+	Code   []Instruction
+}
+
 func (s *Stack) Push(c any) error {
+	var inputs []string
+	if len(s.frames) > 0 {
+		inputs = s.frames[len(s.frames)-1]
+	}
+	return s.push(c, inputs)
+}
+
+func (s *Stack) push(c any, inputs []string) error {
 	if len(s.cells) == cap(s.cells) {
 		return ErrStackOverflow
 	}
@@ -44,12 +69,60 @@ func (s *Stack) Push(c any) error {
 		CellFloat,
 		CellText,
 		CellQuotation,
-		CellTime:
+		CellTime,
+		CellList,
+		CellMap:
+	}
+	if s.maxMemory > 0 {
+		size := cellSize(c)
+		err := s.reserve(size)
+		if err != nil {
+			return err
+		}
+		s.memory += size
 	}
 	s.cells = append(s.cells, c)
+	s.inputs = append(s.inputs, inputs[:len(inputs):len(inputs)])
 	return nil
 }
 
+// SetMemoryLimit sets the maximum memory used by the cells on the stack in bytes (0 means no limit).
+// The memory used by a cell is estimated from the size of its texts and elements.
+func (s *Stack) SetMemoryLimit(n int) {
+	s.maxMemory, s.memory = n, 0
+	for _, c := range s.cells {
+		s.memory += cellSize(c)
+	}
+}
+
+// reserve returns ErrMemoryLimit if a cell of the given size can't be pushed,
+// so that large values can be rejected before they are built.
+func (s *Stack) reserve(size int) error {
+	if s.maxMemory > 0 && s.memory+size > s.maxMemory {
+		return ErrMemoryLimit
+	}
+	return nil
+}
+
+// available returns the number of bytes of text that can be pushed, or -1 if there's no limit.
+func (s *Stack) available() int {
+	if s.maxMemory <= 0 {
+		return -1
+	}
+	if n := s.maxMemory - s.memory - cellOverhead; n > 0 {
+		return n
+	}
+	return 0
+}
+
+func (s *Stack) Len() int { return len(s.cells) }
+
+// Cells returns a copy of the cells on the stack (bottom cell first).
+func (s *Stack) Cells() []any { return append([]any(nil), s.cells...) }
+
+// Clear removes all cells from the stack.
+func (s *Stack) Clear() { s.cells, s.inputs, s.memory = s.cells[:0], s.inputs[:0], 0 }
+
 func (s *Stack) Peek(i int) (any, error) {
 	if len(s.cells)-i == 0 {
 		return nil, ErrStackUnderflow
@@ -58,14 +131,79 @@ func (s *Stack) Peek(i int) (any, error) {
 }
 
 func (s *Stack) Pop() (any, error) {
-	c, err := s.Peek(0)
+	c, inputs, err := s.pop()
 	if err != nil {
 		return nil, err
 	}
-	s.cells = s.cells[:len(s.cells)-1]
+	s.track(inputs...)
 	return c, nil
 }
 
+// pop removes the top cell and returns it with the inputs it was derived from,
+// unlike Pop the inputs are not added to those of the current instruction.
+func (s *Stack) pop() (any, []string, error) {
+	c, err := s.Peek(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	n := len(s.cells) - 1
+	inputs := s.inputs[n]
+	s.cells, s.inputs = s.cells[:n], s.inputs[:n]
+	if s.maxMemory > 0 {
+		s.memory -= cellSize(c)
+	}
+	return c, inputs, nil
+}
+
+// maxTrackedInputs is the maximum number of user inputs a cell can be derived from,
+// additional inputs are not reported.
+const maxTrackedInputs = 64
+
+// enter starts tracking the inputs of the cells popped by an instruction,
+// it returns the depth to give to leave once the instruction is executed.
+func (s *Stack) enter() int {
+	s.frames = append(s.frames, nil)
+	return len(s.frames) - 1
+}
+
+// leave stops tracking the inputs of the instruction started at the given depth (and of those nested in it).
+func (s *Stack) leave(depth int) {
+	if depth < len(s.frames) {
+		s.frames = s.frames[:depth]
+	}
+}
+
+// track adds texts typed by the user to the inputs of the current instruction,
+// the cells it pushes are then derived from them.
+func (s *Stack) track(inputs ...string) {
+	if len(s.frames) == 0 {
+		return
+	}
+	frame := &s.frames[len(s.frames)-1]
+	for _, input := range inputs {
+		if len(*frame) < maxTrackedInputs && !containsText(*frame, input) {
+			*frame = append(*frame, input)
+		}
+	}
+}
+
+// tracked returns the inputs of the cells popped so far by the current instruction.
+func (s *Stack) tracked() []string {
+	if len(s.frames) == 0 {
+		return nil
+	}
+	return s.frames[len(s.frames)-1]
+}
+
+func containsText(texts []string, s string) bool {
+	for _, v := range texts {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func newInvalidTypeError(v any) error {
 	return fmt.Errorf("invalid type %T", v)
 }
@@ -92,39 +230,39 @@ func (s *Stack) Pick() error {
 	if err != nil {
 		return err
 	}
-	return s.Push(cellN)
+	return s.push(cellN, s.inputs[len(s.inputs)-1-int(i)])
 }
 
 func (s *Stack) Swap() error {
-	cellB, err := s.Pop()
+	cellB, inputsB, err := s.pop()
 	if err != nil {
 		return err
 	}
-	cellA, err := s.Pop()
+	cellA, inputsA, err := s.pop()
 	if err != nil {
 		return err
 	}
-	_ = s.Push(cellB)
-	_ = s.Push(cellA)
+	_ = s.push(cellB, inputsB)
+	_ = s.push(cellA, inputsA)
 	return nil
 }
 
 func (s *Stack) Rot() error {
-	cellC, err := s.Pop()
+	cellC, inputsC, err := s.pop()
 	if err != nil {
 		return err
 	}
-	cellB, err := s.Pop()
+	cellB, inputsB, err := s.pop()
 	if err != nil {
 		return err
 	}
-	cellA, err := s.Pop()
+	cellA, inputsA, err := s.pop()
 	if err != nil {
 		return err
 	}
-	_ = s.Push(cellC)
-	_ = s.Push(cellA)
-	_ = s.Push(cellB)
+	_ = s.push(cellC, inputsC)
+	_ = s.push(cellA, inputsA)
+	_ = s.push(cellB, inputsB)
 	return nil
 }
 
@@ -160,6 +298,14 @@ func (s *Stack) IsEqual() error {
 		if b, ok := cellB.(CellTime); ok {
 			return s.Push(CellBoolean(time.Time(a).Equal(time.Time(b))))
 		}
+	case CellList:
+		if b, ok := cellB.(CellList); ok {
+			return s.Push(CellBoolean(isEqualCell(a, b)))
+		}
+	case CellMap:
+		if b, ok := cellB.(CellMap); ok {
+			return s.Push(CellBoolean(isEqualCell(a, b)))
+		}
 	}
 	return newTypeMismatchError(cellA, cellB)
 }
@@ -250,6 +396,10 @@ func (s *Stack) Add() error {
 		}
 	case CellText:
 		if b, ok := cellB.(CellText); ok {
+			err = s.reserve(cellOverhead + len(a) + len(b))
+			if err != nil {
+				return err
+			}
 			return s.Push(CellText(a + b))
 		}
 	}
@@ -369,6 +519,12 @@ func (s *Stack) ToText() error {
 		return s.Push(CellText(strconv.FormatInt(int64(a), 10)))
 	case CellFloat:
 		return s.Push(CellText(strconv.FormatFloat(float64(a), 'f', 5, 64)))
+	case CellList, CellMap:
+		v, err := formatCell(a, false)
+		if err != nil {
+			return err
+		}
+		return s.Push(CellText(v))
 	}
 }
 
@@ -405,3 +561,149 @@ func (s *Stack) Invert() error {
 		return s.Push(!a)
 	}
 }
+
+// compareCells returns -1, 0 or 1 if a is respectively smaller, equal or greater than b.
+func compareCells(a, b any) (int, error) {
+	switch a := a.(type) {
+	default:
+		return 0, newInvalidTypeError(a)
+	case CellInteger:
+		if b, ok := b.(CellInteger); ok {
+			return compareOrdered(a, b), nil
+		}
+	case CellFloat:
+		if b, ok := b.(CellFloat); ok {
+			return compareOrdered(a, b), nil
+		}
+	case CellText:
+		if b, ok := b.(CellText); ok {
+			return compareOrdered(a, b), nil
+		}
+	case CellTime:
+		if b, ok := b.(CellTime); ok {
+			if time.Time(a).Before(time.Time(b)) {
+				return -1, nil
+			} else if time.Time(a).After(time.Time(b)) {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, newTypeMismatchError(a, b)
+}
+
+func compareOrdered[T CellInteger | CellFloat | CellText](a, b T) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// isEqualCell reports whether both cells hold the same value, cells of different types are never equal.
+func isEqualCell(a, b any) bool {
+	switch a := a.(type) {
+	case CellList:
+		b, ok := b.(CellList)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !isEqualCell(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case CellMap:
+		b, ok := b.(CellMap)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := b[k]; !ok || !isEqualCell(v, w) {
+				return false
+			}
+		}
+		return true
+	case CellQuotation:
+		b, ok := b.(CellQuotation)
+		return ok && a.Source == b.Source
+	case CellTime:
+		b, ok := b.(CellTime)
+		return ok && time.Time(a).Equal(time.Time(b))
+	}
+	return a == b
+}
+
+// formatCell returns the textual representation of a cell.
+// Text nested in lists and maps is quoted, so that lists and maps of texts, integers, booleans and quotations
+// can be read back as Jul code. Floats and times have no literal syntax, their output can't be read back.
+func formatCell(c any, nested bool) (string, error) {
+	switch c := c.(type) {
+	default:
+		return "", newInvalidTypeError(c)
+	case CellText:
+		if nested {
+			return QuoteText(string(c)), nil
+		}
+		return string(c), nil
+	case CellBoolean:
+		return strconv.FormatBool(bool(c)), nil
+	case CellInteger:
+		return strconv.Itoa(int(c)), nil
+	case CellFloat:
+		return strconv.FormatFloat(float64(c), 'f', 5, 64), nil
+	case CellTime:
+		return time.Time(c).Format(time.RFC3339), nil
+	case CellQuotation:
+		return string(MarkAnonymousFunctionStart) + c.Source + string(MarkAnonymousFunctionEnd), nil
+	case CellList:
+		items := make([]string, len(c))
+		for i, item := range c {
+			v, err := formatCell(item, true)
+			if err != nil {
+				return "", err
+			}
+			items[i] = v
+		}
+		return string(MarkListStart) + strings.Join(items, " ") + string(MarkListEnd), nil
+	case CellMap:
+		items := make([]string, 0, 2*len(c))
+		for _, k := range c.Keys() {
+			v, err := formatCell(c[k], true)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, QuoteText(k), v)
+		}
+		return string(MarkMapPrefix) + string(MarkListStart) + strings.Join(items, " ") + string(MarkListEnd), nil
+	}
+}
+
+// cellOverhead is the estimated memory used by a cell, in addition to its texts and elements.
+const cellOverhead = 16
+
+// cellSize returns the estimated memory used by a cell in bytes.
+func cellSize(c any) int {
+	switch v := c.(type) {
+	default:
+		return cellOverhead
+	case CellText:
+		return cellOverhead + len(v)
+	case CellQuotation:
+		return cellOverhead + len(v.Source)
+	case CellList:
+		n := cellOverhead
+		for _, e := range v {
+			n += cellSize(e)
+		}
+		return n
+	case CellMap:
+		n := cellOverhead
+		for k, e := range v {
+			n += len(k) + cellSize(e)
+		}
+		return n
+	}
+}
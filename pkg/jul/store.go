@@ -0,0 +1,206 @@
+package jul
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Store is a key-value store used by scripts to persist data on the client.
+// Values are JSON-encoded cells.
+type Store interface {
+	Get(key string) ([]byte, error) // Returns ErrMissingKey if the key doesn't exist
+	Set(key string, value []byte) error
+	Delete(key string) error
+	Keys() ([]string, error)
+}
+
+// MemoryStore is a store that keeps data in memory.
+type MemoryStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore { return &MemoryStore{data: map[string][]byte{}} }
+
+func (s *MemoryStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrMissingKey, key)
+	}
+	return v, nil
+}
+
+func (s *MemoryStore) Set(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+func (s *MemoryStore) Keys() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.data), nil
+}
+
+// ErrStoreQuota is returned when a write would exceed the quota of a store.
+var ErrStoreQuota = errors.New("store quota exceeded")
+
+// Default quota of file stores.
+const (
+	DefaultStoreMaxKeys  = 1000
+	DefaultStoreMaxBytes = 1 << 20
+)
+
+// FileStore is a store that persists data in a JSON file.
+// Each server origin gets its own file so that servers can't access each other's data.
+type FileStore struct {
+	MaxKeys  int // Maximum number of keys of the origin
+	MaxBytes int // Maximum size of the keys and values of the origin
+
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStore returns a store for the given server origin (for example "example.com:8080"),
+// the store file is created in the given directory on first write.
+func NewFileStore(dir, origin string) *FileStore {
+	return &FileStore{
+		MaxKeys:  DefaultStoreMaxKeys,
+		MaxBytes: DefaultStoreMaxBytes,
+		path:     filepath.Join(dir, url.QueryEscape(origin)+".json"),
+	}
+}
+
+// DefaultStoreDir returns the directory used to store client data when none is specified.
+func DefaultStoreDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "jus", "store"), nil
+}
+
+func (s *FileStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	v, ok := data[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrMissingKey, key)
+	}
+	return v, nil
+}
+
+func (s *FileStore) Set(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	data[key] = value
+	if len(data) > s.MaxKeys {
+		return fmt.Errorf("%w: more than %d keys", ErrStoreQuota, s.MaxKeys)
+	}
+	size := 0
+	for k, v := range data {
+		size += len(k) + len(v)
+	}
+	if size > s.MaxBytes {
+		return fmt.Errorf("%w: more than %d bytes", ErrStoreQuota, s.MaxBytes)
+	}
+	return s.save(data)
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := data[key]; !ok {
+		return nil
+	}
+	delete(data, key)
+	return s.save(data)
+}
+
+func (s *FileStore) Keys() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return sortedKeys(data), nil
+}
+
+func (s *FileStore) load() (map[string][]byte, error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string][]byte{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	raw := map[string]json.RawMessage{}
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode store file %s: %w", s.path, err)
+	}
+	data := make(map[string][]byte, len(raw))
+	for k, v := range raw {
+		data[k] = v
+	}
+	return data, nil
+}
+
+// save writes the store file atomically by replacing it with a temporary file.
+func (s *FileStore) save(data map[string][]byte) error {
+	raw := make(map[string]json.RawMessage, len(data))
+	for k, v := range data {
+		raw[k] = v
+	}
+	b, err := json.MarshalIndent(raw, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(s.path), 0o700)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	err = os.WriteFile(tmp, b, 0o600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func sortedKeys(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package jul
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("persists cells in file store", func(t *testing.T) {
+		dir := t.TempDir()
+		vm := NewVM(WithStore(NewFileStore(dir, "example.com:8080")))
+		err := vm.Execute(strings.NewReader(`*customer #{ *name "Ju" *tags { 1 2 } } store-set`))
+		if err != nil {
+			panic(err)
+		}
+
+		vm = NewVM(WithStore(NewFileStore(dir, "example.com:8080")))
+		err = vm.Execute(strings.NewReader(`*customer store-get store-keys *customer store-has`))
+		if err != nil {
+			panic(err)
+		}
+		want := []any{
+			CellMap{"name": CellText("Ju"), "tags": CellList{CellInteger(1), CellInteger(2)}},
+			CellList{CellText("customer")},
+			CellBoolean(true),
+		}
+		got, err := vm.stack.Collect(0)
+		if err != nil {
+			panic(err)
+		}
+		if !isEqualCell(got, CellList(want)) {
+			t.Fatalf("got %#v instead of %#v", got, want)
+		}
+	})
+
+	t.Run("separates server origins", func(t *testing.T) {
+		dir := t.TempDir()
+		err := NewFileStore(dir, "a.example.com:8080").Set("k", []byte("1"))
+		if err != nil {
+			panic(err)
+		}
+		_, err = NewFileStore(dir, "b.example.com:8080").Get("k")
+		if !errors.Is(err, ErrMissingKey) {
+			t.Fatalf("got error %v instead of %v", err, ErrMissingKey)
+		}
+	})
+
+	t.Run("deletes keys", func(t *testing.T) {
+		vm := NewVM()
+		err := vm.Execute(strings.NewReader(`*k 1 store-set *k store-delete *k store-has`))
+		if err != nil {
+			panic(err)
+		}
+		c, err := vm.stack.Pop()
+		if err != nil {
+			panic(err)
+		}
+		if c != CellBoolean(false) {
+			t.Fatalf("got %#v instead of false", c)
+		}
+	})
+}
+
+func TestFileStoreQuota(t *testing.T) {
+	tests := []struct {
+		desc  string
+		key   string
+		value string
+		want  error
+	}{
+		{desc: "new key within quota", key: "c", value: "3"},
+		{desc: "existing key within quota", key: "a", value: "10"},
+		{desc: "too many keys", key: "d", value: "4", want: ErrStoreQuota},
+		{desc: "too many bytes", key: "a", value: `"` + strings.Repeat("a", 100) + `"`, want: ErrStoreQuota},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			s := NewFileStore(dir, "example.com:8080")
+			s.MaxKeys, s.MaxBytes = 3, 20
+			for _, k := range []string{"a", "b"} {
+				err := s.Set(k, []byte("1"))
+				if err != nil {
+					panic(err)
+				}
+			}
+			if test.key == "d" {
+				err := s.Set("c", []byte("3"))
+				if err != nil {
+					panic(err)
+				}
+			}
+			err := s.Set(test.key, []byte(test.value))
+			if !errors.Is(err, test.want) {
+				t.Fatalf("got error %v instead of %v", err, test.want)
+			}
+
+			// Rejected values are not written
+			v, err := NewFileStore(dir, "example.com:8080").Get(test.key)
+			if test.want == nil && string(v) != test.value {
+				t.Fatalf("got value %q and error %v instead of %q", v, err, test.value)
+			} else if test.want != nil && string(v) == test.value {
+				t.Fatalf("got value %q written despite error", v)
+			}
+		})
+	}
+}
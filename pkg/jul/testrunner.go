@@ -0,0 +1,257 @@
+package jul
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrAssertion is returned by assertion words when the assertion fails.
+var ErrAssertion = errors.New("assertion failed")
+
+// errTestDone stops executing the test file once the selected test has passed.
+var errTestDone = errors.New("test done")
+
+// TestResult is the outcome of a test case defined with the "test" word.
+type TestResult struct {
+	Name     string
+	Position Position
+	Err      error // Nil if the test passed
+}
+
+// RunTests executes the tests defined in a test file.
+//
+// Each test runs in a fresh VM that executes the file from the start,
+// so words defined before the test are available, but state is not shared between tests.
+// An error is returned if the file itself fails outside of a test.
+func RunTests(path string) ([]TestResult, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect test cases
+	var results []TestResult
+	vm := newTestVM(func(vm *VM, name string, callback CellQuotation) error {
+		results = append(results, TestResult{Name: name, Position: vm.calls[len(vm.calls)-1].Position})
+		return nil
+	})
+	err = vm.ExecuteNamed(path, bytes.NewReader(code))
+	if err != nil {
+		return nil, err
+	}
+
+	// Run each test case in a fresh VM
+	for i := range results {
+		count := 0
+		vm := newTestVM(func(vm *VM, name string, callback CellQuotation) error {
+			count++
+			if count-1 != i {
+				return nil
+			}
+			err := vm.run(callback.Code)
+			if err != nil {
+				return err
+			}
+			return errTestDone
+		})
+		err = vm.ExecuteNamed(path, bytes.NewReader(code))
+		switch {
+		case errors.Is(err, errTestDone):
+		case err == nil:
+			results[i].Err = errors.New("test was not executed")
+		default:
+			results[i].Err = err
+		}
+	}
+	return results, nil
+}
+
+// FindTestFiles returns the test files (ending with "_test.ju") found in the given paths.
+// Directories are searched recursively.
+func FindTestFiles(paths ...string) ([]string, error) {
+	var files []string
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(path, "_test.ju") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// RunTestCLI runs the test files found in the given paths and reports results to w.
+// It returns false if a test failed.
+func RunTestCLI(w io.Writer, paths ...string) bool {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := FindTestFiles(paths...)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return false
+	}
+
+	ok := true
+	for _, path := range files {
+		results, err := RunTests(path)
+		if err != nil {
+			fmt.Fprintf(w, "FAIL\t%s\n%s\n", path, indent(err.Error()))
+			ok = false
+			continue
+		}
+		failed := 0
+		for _, res := range results {
+			if res.Err != nil {
+				failed++
+				fmt.Fprintf(w, "--- FAIL: %s (%s)\n%s\n", res.Name, res.Position, indent(res.Err.Error()))
+			}
+		}
+		if failed > 0 {
+			fmt.Fprintf(w, "FAIL\t%s\t%d/%d tests failed\n", path, failed, len(results))
+			ok = false
+			continue
+		}
+		fmt.Fprintf(w, "ok\t%s\t%d tests\n", path, len(results))
+	}
+	return ok
+}
+
+func indent(s string) string {
+	return "    " + strings.ReplaceAll(strings.TrimSpace(s), "\n", "\n    ")
+}
+
+// newTestVM returns a VM with assertion words, test cases are passed to the given function.
+// User inputs are given by the test with "test-input", output is checked with "test-output".
+func newTestVM(onTest func(vm *VM, name string, callback CellQuotation) error) *VM {
+	ui := &ScriptedUI{recordOutput: true}
+	d := NewDictionary()
+	for _, w := range testBuiltins(ui, onTest) {
+		_ = d.Define(w)
+	}
+	return NewVM(WithDictionary(d), WithUI(ui))
+}
+
+func testBuiltins(ui *ScriptedUI, onTest func(vm *VM, name string, callback CellQuotation) error) []*Definition {
+	return []*Definition{
+		{
+			Name: "test",
+			Func: func(vm *VM) error {
+				// Pop test body
+				cellB, err := vm.stack.Pop()
+				if err != nil {
+					return fmt.Errorf("pop (B) test body (quotation): %w", err)
+				}
+				callback, ok := cellB.(CellQuotation)
+				if !ok {
+					return fmt.Errorf("got (B) %T instead of quotation", cellB)
+				}
+
+				// Pop test name
+				cellA, err := vm.stack.Pop()
+				if err != nil {
+					return fmt.Errorf("pop (A) test name (text): %w", err)
+				}
+				name, ok := cellA.(CellText)
+				if !ok {
+					return fmt.Errorf("got (A) %T instead of text", cellA)
+				}
+				return onTest(vm, string(name), callback)
+			},
+		},
+		{
+			Name: "assert",
+			Func: func(vm *VM) error {
+				cellA, err := vm.stack.Pop()
+				if err != nil {
+					return fmt.Errorf("pop (A) boolean: %w", err)
+				}
+				boolean, ok := cellA.(CellBoolean)
+				if !ok {
+					return fmt.Errorf("got (A) %T instead of boolean", cellA)
+				}
+				if !boolean {
+					return fmt.Errorf("%w: got false", ErrAssertion)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "assert-equal",
+			Func: func(vm *VM) error {
+				cellB, err := vm.stack.Pop()
+				if err != nil {
+					return fmt.Errorf("pop (B) expected value: %w", err)
+				}
+				cellA, err := vm.stack.Pop()
+				if err != nil {
+					return fmt.Errorf("pop (A) actual value: %w", err)
+				}
+				if !isEqualCell(cellA, cellB) {
+					got, _ := formatCell(cellA, true)
+					want, _ := formatCell(cellB, true)
+					return fmt.Errorf("%w: got %s instead of %s", ErrAssertion, got, want)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "expect-error",
+			Func: func(vm *VM) error {
+				cellA, err := vm.stack.Pop()
+				if err != nil {
+					return fmt.Errorf("pop (A) callback (quotation): %w", err)
+				}
+				callback, ok := cellA.(CellQuotation)
+				if !ok {
+					return fmt.Errorf("got (A) %T instead of quotation", cellA)
+				}
+
+				// Cells pushed by the callback before the error are dropped
+				depth := vm.stack.Len()
+				err = vm.run(callback.Code)
+				if err == nil {
+					return fmt.Errorf("%w: got no error", ErrAssertion)
+				}
+				if vm.stack.Len() > depth {
+					_, _ = vm.stack.Collect(depth)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "test-input",
+			Func: func(vm *VM) error {
+				cellA, err := vm.stack.Pop()
+				if err != nil {
+					return fmt.Errorf("pop (A) input (text): %w", err)
+				}
+				line, ok := cellA.(CellText)
+				if !ok {
+					return fmt.Errorf("got (A) %T instead of text", cellA)
+				}
+				ui.addInput(string(line))
+				return nil
+			},
+		},
+		{
+			Name: "test-output",
+			Func: func(vm *VM) error {
+				return vm.stack.Push(CellText(ui.takeOutput()))
+			},
+		},
+	}
+}
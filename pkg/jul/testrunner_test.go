@@ -0,0 +1,56 @@
+package jul
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunTests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "example_test.ju")
+	code := `
+*double [ 2 multiply ] define
+
+*passes [ 2 double 4 assert-equal ] test
+*fails [ 2 double 5 assert-equal ] test
+*expects-error [ [ unknown-word ] expect-error ] test
+*is-isolated [ *defined-in-test [] define ] test
+*is-isolated-too [ *defined-in-test [] define ] test
+*uses-scripted-ui [
+	*Ju test-input
+	"Name? " write read "Hello " swap add write
+	test-output "Name? Hello Ju" assert-equal
+] test
+*expects-missing-input [
+	[ read ] expect-error
+	"still writing" write test-output "still writing" assert-equal
+] test
+`
+	err := os.WriteFile(path, []byte(code), 0o600)
+	if err != nil {
+		panic(err)
+	}
+
+	results, err := RunTests(path)
+	if err != nil {
+		panic(err)
+	}
+	if len(results) != 7 {
+		t.Fatalf("got %d results instead of %d", len(results), 7)
+	}
+	for _, res := range results {
+		if res.Name == "fails" {
+			if !errors.Is(res.Err, ErrAssertion) {
+				t.Fatalf("got error %v instead of %v", res.Err, ErrAssertion)
+			}
+			if want := (Position{File: path, Line: 5, Column: 36}); res.Position != want {
+				t.Fatalf("got position %s instead of %s", res.Position, want)
+			}
+			continue
+		}
+		if res.Err != nil {
+			t.Fatalf("%s: %s", res.Name, res.Err)
+		}
+	}
+}
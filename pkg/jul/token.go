@@ -16,9 +16,20 @@ type Token struct {
 
 func (t Token) String() string { return fmt.Sprintf("%s (%s) %q", t.Type, t.Position, t.Value) }
 
-type Position struct{ Line, Column int }
+// Position locates a character in the source code.
+// File is empty when the source code was not read from a named file.
+type Position struct {
+	File         string
+	Line, Column int
+}
 
-func (p Position) String() string { return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column) }
+func (p Position) String() string {
+	s := strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	if p.File != "" {
+		s = p.File + ":" + s
+	}
+	return s
+}
 
 type TokenType string
 
@@ -26,6 +37,8 @@ const (
 	TokenTypeEOF             TokenType = "EOF"                // Reached EOF
 	TokenTypeFunctionCall    TokenType = "function-call"      // Function call (incl. literal number)
 	TokenTypeQuotation       TokenType = "anonymous-function" // Raw function body (= quotation)
+	TokenTypeList            TokenType = "list"               // Raw list body
+	TokenTypeMap             TokenType = "map"                // Raw map body
 	TokenTypeLiteralText     TokenType = "literal-text"       // Literal text string
 	TokenTypeLiteralTextWord TokenType = "literal-text-word"  // Text without spaces
 	TokenTypeComment         TokenType = "comment"            // Code comments and remarks
@@ -34,6 +47,9 @@ const (
 const (
 	MarkAnonymousFunctionStart = '['
 	MarkAnonymousFunctionEnd   = ']'
+	MarkListStart              = '{'
+	MarkListEnd                = '}'
+	MarkMapPrefix              = '#' // Followed by the list start mark
 	MarkLiteralTextQuote       = '"'
 	MarkLiteralTextWordStart   = '*'
 	MarkCommentStart           = '('
@@ -46,7 +62,12 @@ type Source struct {
 	p Position
 }
 
-func NewSource(r io.Reader) *Source { return &Source{r: r, p: Position{1, 1}} }
+func NewSource(r io.Reader) *Source { return NewNamedSource("", r) }
+
+// NewNamedSource returns a source whose token positions refer to the given file name.
+func NewNamedSource(name string, r io.Reader) *Source {
+	return &Source{r: r, p: Position{File: name, Line: 1, Column: 1}}
+}
 
 // Next returns the next token from the source.
 // When EOF is reached, a token of type EOF is returned,
@@ -67,11 +88,18 @@ func (src *Source) Next() (Token, error) {
 			continue
 		case c == MarkAnonymousFunctionStart:
 			// Tokenize quotation
-			v, err := src.readEnclosed(MarkAnonymousFunctionStart, MarkAnonymousFunctionEnd)
+			v, err := src.body(MarkAnonymousFunctionStart, MarkAnonymousFunctionEnd)
+			if err != nil {
+				return Token{}, err
+			}
+			return Token{Position: start, Type: TokenTypeQuotation, Value: v}, nil
+		case c == MarkListStart:
+			// Tokenize list
+			v, err := src.body(MarkListStart, MarkListEnd)
 			if err != nil {
 				return Token{}, err
 			}
-			return Token{Position: start, Type: TokenTypeQuotation, Value: string(v)}, nil
+			return Token{Position: start, Type: TokenTypeList, Value: v}, nil
 		case c == MarkCommentStart:
 			// Tokenize comment
 			v, err := src.readEnclosed(MarkCommentStart, MarkCommentEnd)
@@ -85,10 +113,12 @@ func (src *Source) Next() (Token, error) {
 			isEscaped := false
 			for {
 				c, err := src.read()
-				if errors.Is(err, io.EOF) || (c == MarkLiteralTextQuote && !isEscaped) {
-					break
+				if errors.Is(err, io.EOF) {
+					return Token{}, newMissingClosingError(src.p, MarkLiteralTextQuote)
 				} else if err != nil {
 					return Token{}, err
+				} else if c == MarkLiteralTextQuote && !isEscaped {
+					break
 				}
 				if isEscaped {
 					switch c {
@@ -96,6 +126,8 @@ func (src *Source) Next() (Token, error) {
 						c = '\n'
 					case 't':
 						c = '\t'
+					case '0':
+						c = 0
 					}
 					isEscaped = false
 				} else if !isEscaped && c == '\\' {
@@ -112,7 +144,29 @@ func (src *Source) Next() (Token, error) {
 				return Token{}, err
 			}
 			return Token{Position: start, Type: TokenTypeLiteralTextWord, Value: string(rest)}, nil
-		case isPrintable(c) && c != MarkAnonymousFunctionEnd:
+		case c == MarkMapPrefix:
+			// Tokenize map, or call starting with the map prefix
+			next, err := src.read()
+			if err != nil && !errors.Is(err, io.EOF) {
+				return Token{}, err
+			}
+			if err == nil && next == MarkListStart {
+				v, err := src.body(MarkListStart, MarkListEnd)
+				if err != nil {
+					return Token{}, err
+				}
+				return Token{Position: start, Type: TokenTypeMap, Value: v}, nil
+			}
+			v := []byte{c}
+			if err == nil && isNotSpace(next) {
+				rest, err := src.readWhile(isNotSpace)
+				if err != nil {
+					return Token{}, err
+				}
+				v = append(append(v, next), rest...)
+			}
+			return Token{Position: start, Type: TokenTypeFunctionCall, Value: string(v)}, nil
+		case isPrintable(c) && c != MarkAnonymousFunctionEnd && c != MarkListEnd:
 			// Tokenize call
 			v := []byte{c}
 			rest, err := src.readWhile(isNotSpace)
@@ -175,12 +229,11 @@ func (src *Source) readWhile(predicate func(byte) bool) ([]byte, error) {
 func (src *Source) readEnclosed(markStart, markEnd byte) ([]byte, error) {
 	depth := 1
 	var v []byte
-	missingClosingErrMsg := fmt.Sprintf("missing closing character: %q", markEnd)
 	for {
 		c, err := src.read()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
-				return v, syntaxError{Position: src.p, Message: missingClosingErrMsg}
+				return v, newMissingClosingError(src.p, markEnd)
 			}
 			return v, err
 		}
@@ -196,18 +249,125 @@ func (src *Source) readEnclosed(markStart, markEnd byte) ([]byte, error) {
 		v = append(v, c)
 	}
 	if depth > 0 {
-		return v, syntaxError{Position: src.p, Message: missingClosingErrMsg}
+		return v, newMissingClosingError(src.p, markEnd)
 	}
 	return v, nil
 }
 
+// body returns the body of a quotation, list or map.
+// When the source is a body parsed in place, nested bodies are left unread (see parseBody).
+func (src *Source) body(markStart, markEnd byte) (string, error) {
+	r, ok := src.r.(*bodyReader)
+	if !ok {
+		v, err := src.readBody(markStart, markEnd)
+		return string(v), err
+	}
+	end, ok := r.ends[r.off]
+	if !ok {
+		r.index(r.off, markEnd)
+		end, ok = r.ends[r.off]
+	}
+	if !ok || end > r.end {
+		for {
+			_, err := src.read()
+			if errors.Is(err, io.EOF) {
+				return "", newMissingClosingError(src.p, markEnd)
+			} else if err != nil {
+				return "", err
+			}
+		}
+	}
+	return r.text[r.off:end], nil
+}
+
+// readBody reads the body of a quotation, list or map until the closing mark.
+// Unlike readEnclosed, marks found in literal texts and comments are ignored.
+func (src *Source) readBody(markStart, markEnd byte) ([]byte, error) {
+	depth := 1
+	var v []byte
+	s := bodyScanner{isTokenStart: true}
+	for {
+		c, err := src.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return v, newMissingClosingError(src.p, markEnd)
+			}
+			return v, err
+		}
+		if s.scan(c) {
+			if c == markStart {
+				depth++
+			} else if c == markEnd {
+				depth--
+			}
+			if depth == 0 {
+				return v, nil
+			}
+		}
+		v = append(v, c)
+	}
+}
+
+// bodyScanner follows literal texts and comments in bodies, where marks are ignored.
+type bodyScanner struct {
+	isTokenStart, inText, isEscaped bool
+	commentDepth                    int
+}
+
+// scan reports whether the character is outside of literal texts and comments.
+func (s *bodyScanner) scan(c byte) bool {
+	switch {
+	case s.inText:
+		if s.isEscaped {
+			s.isEscaped = false
+		} else if c == '\\' {
+			s.isEscaped = true
+		} else if c == MarkLiteralTextQuote {
+			s.inText, s.isTokenStart = false, true
+		}
+	case s.commentDepth > 0:
+		if c == MarkCommentStart {
+			s.commentDepth++
+		} else if c == MarkCommentEnd {
+			s.commentDepth--
+			s.isTokenStart = s.commentDepth == 0
+		}
+	case s.isTokenStart && c == MarkLiteralTextQuote:
+		s.inText = true
+	case s.isTokenStart && c == MarkCommentStart:
+		s.commentDepth = 1
+	default:
+		s.isTokenStart = isSpace(c) || isMark(c)
+		return true
+	}
+	return false
+}
+
+// ErrUnexpectedEOF is wrapped by syntax errors caused by code that ends before a closing mark,
+// more code may be read to complete it.
+var ErrUnexpectedEOF = errors.New("unexpected end of code")
+
 type syntaxError struct {
 	Position Position
 	Message  string
+	Cause    error
 }
 
 func (err syntaxError) Error() string { return fmt.Sprintf("%s (%s)", err.Message, err.Position) }
+func (err syntaxError) Unwrap() error { return err.Cause }
+
+func newMissingClosingError(p Position, markEnd byte) error {
+	return syntaxError{Position: p, Message: fmt.Sprintf("missing closing character: %q", markEnd), Cause: ErrUnexpectedEOF}
+}
 
 func isSpace(c byte) bool     { return c == ' ' || c == '\n' || c == '\t' }
 func isNotSpace(c byte) bool  { return !isSpace(c) }
 func isPrintable(c byte) bool { return c >= 33 && c <= 126 }
+
+func isMark(c byte) bool {
+	switch c {
+	case MarkAnonymousFunctionStart, MarkAnonymousFunctionEnd, MarkListStart, MarkListEnd:
+		return true
+	}
+	return false
+}
@@ -20,6 +20,22 @@ func TestToken(t *testing.T) {
 				{Position: Position{Line: 1, Column: 12}, Type: TokenTypeEOF},
 			},
 		},
+		{
+			desc:  string(TokenTypeList),
+			input: "{1 {2}}",
+			output: []Token{
+				{Position: Position{Line: 1, Column: 1}, Type: TokenTypeList, Value: "1 {2}"},
+				{Position: Position{Line: 1, Column: 8}, Type: TokenTypeEOF},
+			},
+		},
+		{
+			desc:  string(TokenTypeMap),
+			input: "#{*a {1}}",
+			output: []Token{
+				{Position: Position{Line: 1, Column: 1}, Type: TokenTypeMap, Value: "*a {1}"},
+				{Position: Position{Line: 1, Column: 10}, Type: TokenTypeEOF},
+			},
+		},
 		{
 			desc:  string(TokenTypeLiteralText),
 			input: `"Hello\n\t world!"`,
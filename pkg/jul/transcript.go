@@ -0,0 +1,343 @@
+package jul
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrTranscriptMismatch is returned when a conversation doesn't follow the expected transcript.
+var ErrTranscriptMismatch = errors.New("transcript mismatch")
+
+// TranscriptStep is an expected output or a user input in a transcript.
+type TranscriptStep struct {
+	Input   bool           // True if the step is a user input, false for an expected output
+	Text    string         // User input or expected output
+	Pattern *regexp.Regexp // If set, the output must match this pattern instead of Text
+	Line    int            // Line in the transcript file (0 if unknown)
+}
+
+// TranscriptInput returns a step that answers the next "read" with the given line.
+func TranscriptInput(line string) TranscriptStep { return TranscriptStep{Input: true, Text: line} }
+
+// TranscriptOutput returns a step that expects the given output.
+func TranscriptOutput(text string) TranscriptStep { return TranscriptStep{Text: text} }
+
+// TranscriptOutputMatch returns a step that expects an output matching the given regular expression.
+// The pattern must match the whole output, it panics if the pattern is invalid.
+func TranscriptOutputMatch(pattern string) TranscriptStep {
+	return TranscriptStep{Text: pattern, Pattern: regexp.MustCompile("^(?:" + pattern + ")$")}
+}
+
+func (step TranscriptStep) String() string {
+	switch {
+	case step.Input:
+		return fmt.Sprintf("input %q", step.Text)
+	case step.Pattern != nil:
+		return fmt.Sprintf("output matching %q", step.Text)
+	default:
+		return fmt.Sprintf("output %q", step.Text)
+	}
+}
+
+func (step TranscriptStep) location() string {
+	if step.Line == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (line %d)", step.Line)
+}
+
+// ParseTranscript reads a transcript.
+//
+// Lines starting with "> " are user inputs, lines starting with "~ " are regular expressions
+// and other lines are literal output. Consecutive output lines form a single expected output.
+// A leading backslash is removed, so that output lines starting with ">" or "~" can be escaped.
+func ParseTranscript(r io.Reader) ([]TranscriptStep, error) {
+	var steps []TranscriptStep
+	var lines []string // Output lines of the current step (as patterns if isPattern is true)
+	isPattern := false
+	start := 0
+	flush := func() error {
+		if lines == nil {
+			return nil
+		}
+		step := TranscriptStep{Text: strings.Join(lines, "\n"), Line: start}
+		if isPattern {
+			re, err := regexp.Compile("^(?:" + step.Text + ")$")
+			if err != nil {
+				return fmt.Errorf("line %d: %w", start, err)
+			}
+			step.Pattern = re
+		}
+		steps = append(steps, step)
+		lines, isPattern = nil, false
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == ">" || strings.HasPrefix(line, "> ") {
+			err := flush()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, TranscriptStep{Input: true, Text: strings.TrimPrefix(line[1:], " "), Line: n})
+			continue
+		}
+		if lines == nil {
+			start = n
+		}
+
+		// Literal lines are quoted when the step contains a pattern
+		if strings.HasPrefix(line, "~ ") {
+			if !isPattern {
+				for i := range lines {
+					lines[i] = regexp.QuoteMeta(lines[i])
+				}
+				isPattern = true
+			}
+			lines = append(lines, line[2:])
+			continue
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if isPattern {
+			line = regexp.QuoteMeta(line)
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	err := flush()
+	if err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// ScriptedUI is a UI that follows a transcript, for testing conversations.
+//
+// Output written between two reads is checked against the next expected output when
+// "read" is called (or when Done is called), and the next input is returned.
+// Outputs are compared ignoring trailing whitespace at the end of lines.
+// After the first mismatch, all calls return the same error.
+type ScriptedUI struct {
+	steps []TranscriptStep
+	next  int
+	out   strings.Builder // Output written since the last checked step
+	err   error
+
+	// If set, output is not checked but kept until takeOutput is called,
+	// so the transcript only holds inputs (as in test files, see "test-input" and "test-output").
+	recordOutput bool
+}
+
+// NewScriptedUI returns a UI that follows the given transcript.
+func NewScriptedUI(steps ...TranscriptStep) *ScriptedUI { return &ScriptedUI{steps: steps} }
+
+func (ui *ScriptedUI) Write(msg string) error {
+	if ui.err != nil {
+		return ui.err
+	}
+	ui.out.WriteString(msg)
+	return nil
+}
+
+func (ui *ScriptedUI) Read() (string, error) {
+	err := ui.checkOutput()
+	if err != nil {
+		return "", err
+	}
+	if ui.next >= len(ui.steps) {
+		return "", ui.fail("unexpected read at end of transcript")
+	}
+	step := ui.steps[ui.next]
+	if !step.Input {
+		return "", ui.fail("unexpected read, want %s%s", step, step.location())
+	}
+	ui.next++
+	return step.Text, nil
+}
+
+// ConfirmUpload allows all uploads, as transcripts only describe the conversation.
+func (ui *ScriptedUI) ConfirmUpload(u Upload) (bool, error) { return ui.err == nil, ui.err }
+
+// addInput appends an input to the transcript.
+func (ui *ScriptedUI) addInput(line string) { ui.steps = append(ui.steps, TranscriptInput(line)) }
+
+// takeOutput returns the output written since the last call, when output is recorded.
+func (ui *ScriptedUI) takeOutput() string {
+	out := ui.out.String()
+	ui.out.Reset()
+	return out
+}
+
+// Done checks the remaining output and returns an error if the transcript was not followed until the end.
+func (ui *ScriptedUI) Done() error {
+	err := ui.checkOutput()
+	if err != nil {
+		return err
+	}
+	if ui.next < len(ui.steps) {
+		step := ui.steps[ui.next]
+		return ui.fail("conversation ended, want %s%s", step, step.location())
+	}
+	return nil
+}
+
+// checkOutput checks the output written since the last step against the next expected output.
+func (ui *ScriptedUI) checkOutput() error {
+	if ui.err != nil || ui.recordOutput {
+		return ui.err
+	}
+	got := normalizeOutput(ui.out.String())
+	ui.out.Reset()
+
+	var step TranscriptStep
+	if ui.next < len(ui.steps) {
+		step = ui.steps[ui.next]
+	}
+	if ui.next >= len(ui.steps) || step.Input {
+		if got == "" {
+			return nil
+		}
+		want := "end of transcript"
+		if step.Input {
+			want = step.String() + step.location()
+		}
+		return ui.fail("unexpected output, want %s:\n%s", want, indent(got))
+	}
+	ui.next++
+
+	if step.Pattern != nil {
+		if !step.Pattern.MatchString(got) {
+			return ui.fail("output doesn't match pattern%s:\n%s\ngot:\n%s", step.location(), indent(step.Text), indent(got))
+		}
+		return nil
+	}
+	want := normalizeOutput(step.Text)
+	if got != want {
+		return ui.fail("output differs%s (-want +got):\n%s", step.location(), diffLines(want, got))
+	}
+	return nil
+}
+
+// fail returns a mismatch error, which is returned by all later calls unless output is recorded
+// (then tests can expect the error and go on).
+func (ui *ScriptedUI) fail(format string, args ...any) error {
+	err := fmt.Errorf("%w: "+format, append([]any{ErrTranscriptMismatch}, args...)...)
+	if !ui.recordOutput {
+		ui.err = err
+	}
+	return err
+}
+
+// normalizeOutput removes trailing whitespace at the end of lines and trailing empty lines.
+func normalizeOutput(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// diffLines returns a line diff between a and b, removed lines are prefixed with "-" and added lines with "+".
+func diffLines(a, b string) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// Length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			diff.WriteString("    " + x[i] + "\n")
+			i, j = i+1, j+1
+		case j >= len(y) || (i < len(x) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff.WriteString("  - " + x[i] + "\n")
+			i++
+		default:
+			diff.WriteString("  + " + y[j] + "\n")
+			j++
+		}
+	}
+	return strings.TrimSuffix(diff.String(), "\n")
+}
+
+// RunTranscript executes code with a scripted UI and checks that the conversation follows the transcript.
+func RunTranscript(name string, code io.Reader, steps []TranscriptStep, opts ...Option) error {
+	ui := NewScriptedUI(steps...)
+	err := NewVM(append(opts, WithUI(ui))...).ExecuteNamed(name, code)
+	if err != nil && !errors.Is(err, ErrTranscriptMismatch) {
+		return err
+	}
+	return ui.Done()
+}
+
+// RunTranscriptCLI runs a Jul file against a transcript file and reports the result to w.
+// Arguments are "[-seed n] <file.ju> <transcript>", it returns false if the conversation doesn't match.
+func RunTranscriptCLI(w io.Writer, args ...string) bool {
+	fset := flag.NewFlagSet("transcript", flag.ContinueOnError)
+	fset.SetOutput(w)
+	seed := fset.Int64("seed", 0, "seed used by the \"random\" word")
+	fset.Usage = func() {
+		fmt.Fprintln(w, "Usage: transcript [-seed n] <file.ju> <transcript>")
+		fset.PrintDefaults()
+	}
+	if fset.Parse(args) != nil {
+		return false
+	}
+	if fset.NArg() != 2 {
+		fset.Usage()
+		return false
+	}
+	codePath, transcriptPath := fset.Arg(0), fset.Arg(1)
+
+	f, err := os.Open(transcriptPath)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return false
+	}
+	defer f.Close()
+	steps, err := ParseTranscript(f)
+	if err != nil {
+		fmt.Fprintf(w, "%s: %s\n", transcriptPath, err)
+		return false
+	}
+
+	code, err := os.Open(codePath)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return false
+	}
+	defer code.Close()
+	err = RunTranscript(codePath, code, steps, WithRandomSeed(*seed))
+	if err != nil {
+		fmt.Fprintf(w, "FAIL\t%s\t%s\n%s\n", codePath, transcriptPath, indent(err.Error()))
+		return false
+	}
+	fmt.Fprintf(w, "ok\t%s\t%s\n", codePath, transcriptPath)
+	return true
+}
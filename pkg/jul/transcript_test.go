@@ -0,0 +1,106 @@
+package jul
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseTranscript(t *testing.T) {
+	steps, err := ParseTranscript(strings.NewReader("Name?\n> Ju\nHello Ju\n~ [0-9]+ messages\n\\> quoted\n>\n"))
+	if err != nil {
+		panic(err)
+	}
+	if len(steps) != 4 {
+		t.Fatalf("got %d steps instead of %d", len(steps), 4)
+	}
+	if !steps[1].Input || steps[1].Text != "Ju" || steps[1].Line != 2 {
+		t.Fatalf("got %+v instead of input %q on line 2", steps[1], "Ju")
+	}
+	if steps[2].Pattern == nil || !steps[2].Pattern.MatchString("Hello Ju\n12 messages\n> quoted") {
+		t.Fatalf("got pattern %v", steps[2].Pattern)
+	}
+	if !steps[3].Input || steps[3].Text != "" {
+		t.Fatalf("got %+v instead of empty input", steps[3])
+	}
+
+	_, err = ParseTranscript(strings.NewReader("~ (\n"))
+	if err == nil {
+		t.Fatalf("got no error for invalid pattern")
+	}
+}
+
+func TestScriptedUI(t *testing.T) {
+	code := `"Name? " write read "Hello " swap add "!\n" add write`
+
+	t.Run("follows transcript", func(t *testing.T) {
+		err := RunTranscript("test", strings.NewReader(code), []TranscriptStep{
+			TranscriptOutput("Name?"),
+			TranscriptInput("Ju"),
+			TranscriptOutputMatch(`Hello \w+!`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		steps []TranscriptStep
+		want  string
+	}{
+		{
+			name:  "different output",
+			steps: []TranscriptStep{TranscriptOutput("Name?"), TranscriptInput("Ju"), TranscriptOutput("Hi Ju!")},
+			want:  "output differs (-want +got):\n  - Hi Ju!\n  + Hello Ju!",
+		},
+		{
+			name:  "unmatched pattern",
+			steps: []TranscriptStep{TranscriptOutputMatch(`Age\?`)},
+			want:  "output doesn't match pattern",
+		},
+		{
+			name:  "unexpected read",
+			steps: []TranscriptStep{TranscriptOutput("Name?")},
+			want:  "unexpected read at end of transcript",
+		},
+		{
+			name:  "missing steps",
+			steps: []TranscriptStep{TranscriptOutput("Name?"), TranscriptInput("Ju"), TranscriptOutput("Hello Ju!"), TranscriptInput("Bye")},
+			want:  `conversation ended, want input "Bye"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := RunTranscript("test", strings.NewReader(code), test.steps)
+			if !errors.Is(err, ErrTranscriptMismatch) {
+				t.Fatalf("got error %v instead of %v", err, ErrTranscriptMismatch)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got error %q, want it to contain %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestNumberGuesserTranscript(t *testing.T) {
+	f, err := os.Open("../../examples/number-guesser.transcript")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	steps, err := ParseTranscript(f)
+	if err != nil {
+		panic(err)
+	}
+	code, err := os.Open("../../examples/number-guesser.ju")
+	if err != nil {
+		panic(err)
+	}
+	defer code.Close()
+	err = RunTranscript(code.Name(), code, steps, WithRandomSeed(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
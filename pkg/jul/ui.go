@@ -2,18 +2,35 @@ package jul
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
 type UI interface {
 	Write(msg string) error
 	Read() (string, error)
+
+	// ConfirmUpload shows the data that is about to be sent to the server
+	// and returns true if the user agrees to send it.
+	ConfirmUpload(u Upload) (bool, error)
+}
+
+// BoundedReader is implemented by UIs that can stop reading lines that are too long.
+type BoundedReader interface {
+	// ReadMax is like Read but returns ErrMemoryLimit if the line is longer than n bytes.
+	ReadMax(n int) (string, error)
 }
 
+// DefaultUI reads user input from a reader and writes messages to a writer,
+// formatted messages use ANSI styles if the writer is a terminal.
 type DefaultUI struct {
-	r *bufio.Reader
-	w io.Writer
+	r    *bufio.Reader
+	w    io.Writer
+	ansi bool
 }
 
 func NewDefaultUI(r io.Reader, w io.Writer) *DefaultUI {
@@ -23,18 +40,80 @@ func NewDefaultUI(r io.Reader, w io.Writer) *DefaultUI {
 	if w == nil {
 		w = os.Stdout
 	}
-	return &DefaultUI{r: bufio.NewReader(r), w: w}
+	return &DefaultUI{r: bufio.NewReader(r), w: w, ansi: isTerminal(w)}
 }
 
+// Write writes the message, control characters are removed so that scripts can't send escape sequences.
 func (ui *DefaultUI) Write(msg string) error {
-	_, err := io.WriteString(ui.w, msg)
+	_, err := io.WriteString(ui.w, sanitizeText(msg))
 	return err
 }
 
-func (ui *DefaultUI) Read() (string, error) {
-	line, err := ui.r.ReadBytes('\n')
-	if err != nil {
-		return "", err
+func (ui *DefaultUI) WriteFormatted(msg Message) error {
+	if !ui.ansi {
+		return ui.Write(msg.PlainText())
+	}
+	_, err := io.WriteString(ui.w, msg.ANSI())
+	return err
+}
+
+func (ui *DefaultUI) Read() (string, error) { return ui.ReadMax(-1) }
+
+// ReadMax is like Read but returns ErrMemoryLimit if the line is longer than n bytes (n < 0 means no limit).
+// The rest of a line that is too long is discarded.
+func (ui *DefaultUI) ReadMax(n int) (string, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := ui.r.ReadSlice('\n')
+		if !tooLong {
+			line = append(line, chunk...)
+			tooLong = n >= 0 && len(bytes.TrimSuffix(line, []byte("\n"))) > n
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		break
+	}
+	if tooLong {
+		return "", fmt.Errorf("%w: line longer than %d bytes", ErrMemoryLimit, n)
 	}
 	return string(line[:len(line)-1]), nil
 }
+
+// AskPermission asks the user to grant a capability to the given origin.
+func (ui *DefaultUI) AskPermission(origin string, c Capability) (bool, error) {
+	err := ui.Write(fmt.Sprintf("\nAllow %s to use %s? [y/N] ", origin, c.Description()))
+	if err != nil {
+		return false, err
+	}
+	answer, err := ui.Read()
+	if err != nil {
+		return false, err
+	}
+	return isYes(answer), nil
+}
+
+// ConfirmUpload shows the data that is about to be sent and asks the user to confirm.
+func (ui *DefaultUI) ConfirmUpload(u Upload) (bool, error) {
+	msg := fmt.Sprintf("\nSend this to %s?\n    %q\n", u.Origin, u.Data)
+	if len(u.UserInputs) > 0 {
+		msg += fmt.Sprintf("It includes what you typed: %q\n", u.UserInputs)
+	}
+	err := ui.Write(msg + "[y/N] ")
+	if err != nil {
+		return false, err
+	}
+	answer, err := ui.Read()
+	if err != nil {
+		return false, err
+	}
+	return isYes(answer), nil
+}
+
+func isYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,164 @@
+//go:build js && wasm
+
+package jul
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+)
+
+// JSUI is a UI backed by the methods of a JavaScript object, for browsers.
+//
+// The object must implement:
+//   - write(text)
+//   - read(), returning the text typed by the user (or a promise of it)
+//   - confirmUpload({origin, data, userInputs}), returning a boolean (or a promise of it)
+//
+// It may also implement writeFormatted(spans), choose(prompt, options), confirm(prompt),
+// input(prompt, kind) and askPermission(origin, description), otherwise text prompts are used.
+// Spans are objects with the same fields as Span in JSON ({text, style, url}).
+//
+// Methods may return promises, so they must not be called from the JavaScript event loop (use a goroutine).
+type JSUI struct{ v js.Value }
+
+// NewJSUI returns a UI backed by the given JavaScript object.
+func NewJSUI(v js.Value) *JSUI { return &JSUI{v: v} }
+
+// call calls a method of the object and waits for the returned promise (if any).
+func (ui *JSUI) call(method string, args ...any) (js.Value, error) {
+	if ui.v.Get(method).Type() != js.TypeFunction {
+		return js.Undefined(), fmt.Errorf("UI has no %q method", method)
+	}
+	return awaitJS(ui.v.Call(method, args...))
+}
+
+func (ui *JSUI) has(method string) bool { return ui.v.Get(method).Type() == js.TypeFunction }
+
+func (ui *JSUI) Write(msg string) error {
+	_, err := ui.call("write", msg)
+	return err
+}
+
+func (ui *JSUI) WriteFormatted(msg Message) error {
+	if !ui.has("writeFormatted") {
+		return ui.Write(msg.PlainText())
+	}
+	spans := make([]any, len(msg))
+	for i, span := range msg {
+		spans[i] = map[string]any{"text": span.Text, "style": string(span.Style), "url": span.URL}
+	}
+	_, err := ui.call("writeFormatted", spans)
+	return err
+}
+
+func (ui *JSUI) Read() (string, error) {
+	v, err := ui.call("read")
+	if err != nil {
+		return "", err
+	}
+	if v.Type() != js.TypeString {
+		return "", fmt.Errorf("read returned %s instead of string", v.Type())
+	}
+	return v.String(), nil
+}
+
+func (ui *JSUI) ConfirmUpload(u Upload) (bool, error) {
+	inputs := make([]any, len(u.UserInputs))
+	for i, input := range u.UserInputs {
+		inputs[i] = input
+	}
+	v, err := ui.call("confirmUpload", map[string]any{"origin": u.Origin, "data": u.Data, "userInputs": inputs})
+	if err != nil {
+		return false, err
+	}
+	return v.Truthy(), nil
+}
+
+func (ui *JSUI) Choose(prompt string, options []string) (int, error) {
+	if !ui.has("choose") {
+		return TextUI{ui}.Choose(prompt, options)
+	}
+	values := make([]any, len(options))
+	for i, option := range options {
+		values[i] = option
+	}
+	v, err := ui.call("choose", prompt, values)
+	if err != nil {
+		return 0, err
+	}
+	if v.Type() != js.TypeNumber || v.Int() < 0 || v.Int() >= len(options) {
+		return 0, fmt.Errorf("choose returned invalid option index %s", v.String())
+	}
+	return v.Int(), nil
+}
+
+func (ui *JSUI) Confirm(prompt string) (bool, error) {
+	if !ui.has("confirm") {
+		return TextUI{ui}.Confirm(prompt)
+	}
+	v, err := ui.call("confirm", prompt)
+	if err != nil {
+		return false, err
+	}
+	return v.Truthy(), nil
+}
+
+func (ui *JSUI) Input(prompt string, kind InputKind) (string, error) {
+	if !ui.has("input") {
+		return TextUI{ui}.Input(prompt, kind)
+	}
+	v, err := ui.call("input", prompt, string(kind))
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
+func (ui *JSUI) AskPermission(origin string, c Capability) (bool, error) {
+	if !ui.has("askPermission") {
+		return ui.Confirm(fmt.Sprintf("Allow %s to use %s?", origin, c.Description()))
+	}
+	v, err := ui.call("askPermission", origin, c.Description())
+	if err != nil {
+		return false, err
+	}
+	return v.Truthy(), nil
+}
+
+// awaitJS waits for the value to be resolved if it is a promise (or any "thenable"),
+// a rejected promise is returned as an error.
+func awaitJS(v js.Value) (js.Value, error) {
+	if v.Type() != js.TypeObject || v.Get("then").Type() != js.TypeFunction {
+		return v, nil
+	}
+	type result struct {
+		v   js.Value
+		err error
+	}
+	done := make(chan result, 1)
+	onResolve := js.FuncOf(func(this js.Value, args []js.Value) any {
+		done <- result{v: jsArg(args)}
+		return nil
+	})
+	defer onResolve.Release()
+	onReject := js.FuncOf(func(this js.Value, args []js.Value) any {
+		reason := jsArg(args)
+		if reason.Type() == js.TypeObject && reason.Get("message").Type() == js.TypeString {
+			reason = reason.Get("message")
+		}
+		done <- result{err: errors.New(reason.String())}
+		return nil
+	})
+	defer onReject.Release()
+	v.Call("then", onResolve, onReject)
+	res := <-done
+	return res.v, res.err
+}
+
+func jsArg(args []js.Value) js.Value {
+	if len(args) == 0 {
+		return js.Undefined()
+	}
+	return args[0]
+}
@@ -0,0 +1,90 @@
+package jul
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+// ErrUploadDenied is returned by "retrieve" when the user refuses to send data to the server.
+var ErrUploadDenied = fmt.Errorf("%w: upload refused by user", ErrPermissionDenied)
+
+// Upload is data that a script sends to the server with "retrieve",
+// it is shown to the user for confirmation before being sent.
+// User inputs are tracked through the cells derived from them, but not through storage
+// (texts read with "store-get" are not reported).
+type Upload struct {
+	Origin     string   // Server the data is sent to
+	Data       string   // Exact message sent to the server
+	UserInputs []string // Texts typed by the user (with "read" or "ask-*") that the data was derived from
+}
+
+// AuditEntry is a line of the audit log, in JSON.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Origin     string    `json:"origin"`
+	Data       string    `json:"data"`
+	UserInputs []string  `json:"user_inputs,omitempty"`
+	Allowed    bool      `json:"allowed"`
+}
+
+// WithAuditLog sets where uploads are logged, each upload (allowed or not) is written as a line of JSON.
+func WithAuditLog(w io.Writer) Option { return func(vm *VM) { vm.audit = w } }
+
+// OpenAuditLog opens the audit log of the given server origin for appending,
+// each origin gets its own file in the given directory.
+func OpenAuditLog(dir, origin string) (*os.File, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(dir, url.QueryEscape(origin)+".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+}
+
+// trackInput remembers a text typed by the user,
+// the cells pushed by the current word are derived from it.
+func (vm *VM) trackInput(line string) {
+	if line != "" {
+		vm.stack.track(line)
+	}
+}
+
+// upload asks the user to confirm the upload, logs it and sends it to the server.
+func (vm *VM) upload(data string) error {
+	origin := vm.origin
+	if origin == "" {
+		origin = vm.conn.RemoteAddr().String()
+	}
+	u := Upload{Origin: origin, Data: data, UserInputs: vm.stack.tracked()}
+	allowed, err := vm.ui.ConfirmUpload(u)
+	if err != nil {
+		return fmt.Errorf("confirm upload: %w", err)
+	}
+	if vm.audit != nil {
+		b, err := json.Marshal(AuditEntry{
+			Time:       time.Now(),
+			Origin:     u.Origin,
+			Data:       u.Data,
+			UserInputs: u.UserInputs,
+			Allowed:    allowed,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = vm.audit.Write(append(b, '\n'))
+		if err != nil {
+			return fmt.Errorf("write audit log: %w", err)
+		}
+	}
+	if !allowed {
+		return ErrUploadDenied
+	}
+	_, err = jutp.Write(vm.conn, jutp.Message(data))
+	return err
+}
@@ -0,0 +1,102 @@
+package jul
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+func TestRetrieveConsent(t *testing.T) {
+	run := func(code, input string) (sent []string, audit []AuditEntry, out string, err error) {
+		client, server := net.Pipe()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			r := bufio.NewReader(server)
+			for {
+				msg, err := jutp.Read(r)
+				if err != nil {
+					return
+				}
+				sent = append(sent, string(msg))
+			}
+		}()
+
+		log := &strings.Builder{}
+		w := &strings.Builder{}
+		ui := NewDefaultUI(strings.NewReader(input), w)
+		vm := NewVM(WithUI(ui), WithServerConnection(client), WithOrigin("example.com:8080"), WithAuditLog(log))
+		err = vm.Execute(strings.NewReader(code))
+		client.Close()
+		<-done
+
+		for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+			var entry AuditEntry
+			if json.Unmarshal([]byte(line), &entry) != nil {
+				t.Fatalf("invalid audit log line %q", line)
+			}
+			audit = append(audit, entry)
+		}
+		return sent, audit, w.String(), err
+	}
+
+	t.Run("sends data after confirmation", func(t *testing.T) {
+		sent, audit, out, err := run(`read "Name: " swap add retrieve`, "Ju\ny\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sent) != 1 || sent[0] != "Name: Ju" {
+			t.Fatalf("got sent messages %q", sent)
+		}
+		if !strings.Contains(out, `"Name: Ju"`) || !strings.Contains(out, `It includes what you typed: ["Ju"]`) {
+			t.Fatalf("got output %q", out)
+		}
+		if !strings.Contains(out, "Send this to example.com:8080?") {
+			t.Fatalf("got output %q without the origin", out)
+		}
+		if len(audit) != 1 || !audit[0].Allowed || audit[0].Origin != "example.com:8080" || audit[0].Data != "Name: Ju" || len(audit[0].UserInputs) != 1 {
+			t.Fatalf("got audit log %+v", audit)
+		}
+	})
+
+	t.Run("doesn't send refused data", func(t *testing.T) {
+		sent, audit, _, err := run(`read "Name: " swap add retrieve`, "Ju\nn\n")
+		if !errors.Is(err, ErrUploadDenied) {
+			t.Fatalf("got error %v instead of %v", err, ErrUploadDenied)
+		}
+		if len(sent) != 0 {
+			t.Fatalf("got sent messages %q", sent)
+		}
+		if len(audit) != 1 || audit[0].Allowed {
+			t.Fatalf("got audit log %+v", audit)
+		}
+	})
+
+	t.Run("reports the inputs the data was derived from", func(t *testing.T) {
+		tests := []struct {
+			code   string
+			input  string
+			inputs []string
+		}{
+			{code: `read length to-text retrieve`, input: "Ju\ny\n", inputs: []string{"Ju"}},
+			{code: `read 0 pick add {"a" 1 pick} retrieve`, input: "Ju\ny\n", inputs: []string{"Ju"}},
+			{code: `read read add retrieve`, input: "a\nb\ny\n", inputs: []string{"b", "a"}},
+			{code: `read drop "you typed nothing" retrieve`, input: "y\ny\n", inputs: nil},
+		}
+		for _, test := range tests {
+			_, audit, _, err := run(test.code, test.input)
+			if err != nil {
+				t.Fatalf("%s: %v", test.code, err)
+			}
+			if len(audit) != 1 || !reflect.DeepEqual(audit[0].UserInputs, test.inputs) {
+				t.Fatalf("%s: got audit log %+v instead of user inputs %q", test.code, audit, test.inputs)
+			}
+		}
+	})
+}
@@ -1,13 +1,15 @@
 package jul
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"math/rand"
 	"net"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -18,25 +20,45 @@ import (
 var Prelude string
 
 type VM struct {
-	stack      *Stack
-	dictionary *Dictionary
-	rrand      *rand.Rand
-	ui         UI
-	conn       *net.TCPConn
+	stack       *Stack
+	dictionary  *Dictionary
+	rrand       *rand.Rand
+	ui          UI
+	conn        net.Conn
+	origin      string // Server origin, as configured by the client (defaults to the connection remote address)
+	store       Store
+	permissions *Permissions // Capabilities granted to the code (nil if all are granted)
+	calls       []Frame      // Words currently being executed (innermost last)
+	audit       io.Writer    // Log of uploads (optional)
+
+	// Execution limits
+	maxSteps     int
+	timeout      time.Duration
+	maxCallDepth int
+	maxMemory    int
+	ctx          context.Context // Context of the ongoing execution
+	done         <-chan struct{} // Done channel of ctx (nil if it can't be cancelled)
+	deadline     time.Time       // Deadline set by the VM timeout (zero if none)
+	steps        int             // Instructions executed by the ongoing execution
 }
 
 type Option func(vm *VM)
 
-func WithStack(s *Stack) Option                  { return func(vm *VM) { vm.stack = s } }
-func WithDictionary(d *Dictionary) Option        { return func(vm *VM) { vm.dictionary = d } }
-func WithUI(ui UI) Option                        { return func(vm *VM) { vm.ui = ui } }
-func WithServerConnection(c *net.TCPConn) Option { return func(vm *VM) { vm.conn = c } }
+func WithStack(s *Stack) Option              { return func(vm *VM) { vm.stack = s } }
+func WithDictionary(d *Dictionary) Option    { return func(vm *VM) { vm.dictionary = d } }
+func WithUI(ui UI) Option                    { return func(vm *VM) { vm.ui = ui } }
+func WithServerConnection(c net.Conn) Option { return func(vm *VM) { vm.conn = c } }
+func WithStore(s Store) Option               { return func(vm *VM) { vm.store = s } }
+
+// WithOrigin sets the server origin shown to the user when data is sent and written to the audit log,
+// it should be the origin of the permissions, store and audit log of the server.
+func WithOrigin(origin string) Option { return func(vm *VM) { vm.origin = origin } }
 func WithRandomSeed(seed int64) Option {
 	return func(vm *VM) { vm.rrand = rand.New(rand.NewSource(seed)) }
 }
 
 func NewVM(opts ...Option) *VM {
-	vm := &VM{}
+	vm := &VM{maxCallDepth: DefaultMaxCallDepth, ctx: context.Background()}
 	for _, opt := range opts {
 		opt(vm)
 	}
@@ -51,87 +73,184 @@ func NewVM(opts ...Option) *VM {
 	if vm.ui == nil {
 		vm.ui = NewDefaultUI(nil, nil)
 	}
+	if vm.store == nil {
+		vm.store = NewMemoryStore()
+	}
 	if vm.rrand == nil {
 		vm.rrand = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
+	if vm.maxMemory > 0 {
+		vm.stack.SetMemoryLimit(vm.maxMemory)
+	}
 
-	// Execute prelude
-	err := vm.Execute(strings.NewReader(Prelude))
+	// Execute prelude (without the step and time limits meant for user code)
+	maxSteps, timeout := vm.maxSteps, vm.timeout
+	vm.maxSteps, vm.timeout = 0, 0
+	err := vm.ExecuteNamed("prelude.ju", strings.NewReader(Prelude))
 	if err != nil {
 		panic(err)
 	}
+	vm.maxSteps, vm.timeout = maxSteps, timeout
 
 	return vm
 }
 
-func (vm *VM) Execute(r io.Reader) error {
-	src := NewSource(r)
+// Execute compiles and executes the code from the given reader.
+// Top-level nodes are executed as soon as they are parsed,
+// so code can be streamed from an interactive reader.
+func (vm *VM) Execute(r io.Reader) error { return vm.ExecuteNamed("", r) }
+
+// ExecuteNamed is like Execute but positions reported in errors refer to the given file name.
+func (vm *VM) ExecuteNamed(name string, r io.Reader) error {
+	return vm.ExecuteNamedContext(context.Background(), name, r)
+}
+
+// ExecuteContext is like Execute but stops when the context is cancelled.
+//
+// The execution also stops when a limit set with WithMaxSteps, WithTimeout or WithMaxCallDepth is reached,
+// the returned error then wraps ErrLimitExceeded. These limits apply to each call.
+func (vm *VM) ExecuteContext(ctx context.Context, r io.Reader) error {
+	return vm.ExecuteNamedContext(ctx, "", r)
+}
+
+// ExecuteNamedContext is like ExecuteContext but positions reported in errors refer to the given file name.
+func (vm *VM) ExecuteNamedContext(ctx context.Context, name string, r io.Reader) error {
+	// Restore the state of the ongoing execution when called from a word
+	prevCtx, prevDeadline, prevSteps := vm.ctx, vm.deadline, vm.steps
+	defer func() {
+		vm.ctx, vm.done, vm.deadline, vm.steps = prevCtx, prevCtx.Done(), prevDeadline, prevSteps
+	}()
+
+	vm.ctx, vm.deadline, vm.steps = ctx, time.Time{}, 0
+	if vm.timeout > 0 {
+		var cancel context.CancelFunc
+		vm.deadline = time.Now().Add(vm.timeout)
+		vm.ctx, cancel = context.WithDeadline(ctx, vm.deadline)
+		defer cancel()
+	}
+	vm.done = vm.ctx.Done()
+
+	p := NewNamedParser(name, r)
 	for {
-		tok, err := src.Next()
+		n, err := p.Next()
 		if err != nil {
 			return err
 		}
-		switch tok.Type {
-		default:
-			panic(fmt.Errorf("unreachable: unhandled token type %q", tok.Type))
-		case TokenTypeEOF:
+		if n.Type == TokenTypeEOF {
 			return nil
-		case TokenTypeComment:
+		}
+		ins, ok := vm.compileNode(n)
+		if !ok {
 			continue
-		case TokenTypeFunctionCall:
-			w := vm.dictionary.FindLatestDefinition(tok.Value)
-			if w == nil {
-				num, err := strconv.Atoi(tok.Value)
-				if err != nil {
-					return RuntimeError{Position: src.p, Cause: fmt.Errorf("unknown word %q", tok.Value)}
-				}
-				err = vm.stack.Push(CellInteger(int(num)))
-				if err != nil {
-					return RuntimeError{Position: src.p, Cause: err}
-				}
-				continue
-			}
-			err = w.Func(vm)
-			if err != nil {
-				return RuntimeError{Position: src.p, Cause: fmt.Errorf("%s: %w", w.Name, err)}
-			}
-		case TokenTypeQuotation:
-			err = vm.stack.Push(CellQuotation(tok.Value))
-			if err != nil {
-				return RuntimeError{Position: src.p, Cause: err}
-			}
-		case TokenTypeLiteralText, TokenTypeLiteralTextWord:
-			err = vm.stack.Push(CellText(tok.Value))
-			if err != nil {
-				return RuntimeError{Position: src.p, Cause: err}
-			}
+		}
+		err = vm.run([]Instruction{ins})
+		if err != nil {
+			return err
 		}
 	}
 }
 
+// RuntimeError is returned when executing an instruction fails.
+// The call stack lists the word that failed first, followed by the words it was called from.
 type RuntimeError struct {
 	Position Position
 	Cause    error
+	Stack    []Frame
+}
+
+func (err RuntimeError) Error() string {
+	out := fmt.Sprintf("\n(at %s) %s", err.Position, err.Cause)
+	if len(err.Stack) > 0 {
+		out += "\n\ncall stack:\n" + err.StackTrace()
+	}
+	return out
 }
 
-func (err RuntimeError) Error() string { return fmt.Sprintf("\n(at %s) %s", err.Position, err.Cause) }
+func (err RuntimeError) Unwrap() error { return err.Cause }
+
+// StackTrace formats the call stack like a Go stack trace.
+// Frames in the middle of deep call stacks are elided.
+func (err RuntimeError) StackTrace() string {
+	out := ""
+	for i, f := range err.Stack {
+		if len(err.Stack) > maxStackTraceFrames && i == maxStackTraceFrames/2 {
+			out += fmt.Sprintf("...%d frames elided...\n", len(err.Stack)-maxStackTraceFrames)
+		}
+		if len(err.Stack) > maxStackTraceFrames && i >= maxStackTraceFrames/2 && i < len(err.Stack)-maxStackTraceFrames/2 {
+			continue
+		}
+		out += f.Name + "\n\t" + f.Position.String() + "\n"
+	}
+	return out
+}
+
+const maxStackTraceFrames = 50
+
+// Frame is a word call in the call stack, the position refers to the call site.
+type Frame struct {
+	Name     string
+	Position Position
+}
 
 func RunCLI() {
-	// Start in REPL or file mode
-	from := os.Stdin
+	// Run tests
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		if !RunTestCLI(os.Stdout, os.Args[2:]...) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Check a conversation against a transcript
+	if len(os.Args) > 1 && os.Args[1] == "transcript" {
+		if !RunTranscriptCLI(os.Stdout, os.Args[2:]...) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Execute file if provided
 	if len(os.Args) > 1 {
-		var err error
-		from, err = os.Open(os.Args[1])
+		f, err := os.Open(os.Args[1])
 		if err != nil {
 			log.Fatal(err)
 		}
-		defer from.Close()
+		defer f.Close()
+		err = NewVM().ExecuteNamed(f.Name(), f)
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	// Execute piped code as a single stream
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice == 0 {
+		err = NewVM().ExecuteNamed(os.Stdin.Name(), os.Stdin)
+		if err != nil {
+			log.Println(err)
+		}
+		return
 	}
 
-	// Execute code from stdin or file
-	vm := NewVM()
-	err := vm.Execute(from)
+	// Start REPL, user input and code are read from the same buffered reader
+	editor := NewLineEditor(os.Stdin, os.Stdout)
+	r := bufio.NewReader(editor)
+	repl := NewREPL(NewVM(WithUI(NewDefaultUI(r, os.Stdout))), r, os.Stdout)
+	if home, err := os.UserHomeDir(); err == nil {
+		err = repl.WithHistoryFile(filepath.Join(home, ".jul_history"))
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	// Previous entries can be edited again, except multi-line entries (executed again with "!N")
+	for _, entry := range repl.history {
+		if !strings.Contains(entry, "\n") {
+			editor.History = append(editor.History, entry)
+		}
+	}
+	err := repl.Run()
 	if err != nil {
-		log.Println(err)
+		log.Fatal(err)
 	}
 }
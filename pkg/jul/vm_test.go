@@ -0,0 +1,48 @@
+package jul
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRuntimeError(t *testing.T) {
+	t.Run("reports call stack with positions in the original source", func(t *testing.T) {
+		vm := NewVM()
+		input := "*inner [ 1 \"a\" add ] define\n*outer [\n\ttrue [ inner ] [ ] if\n] define\nouter"
+		err := vm.ExecuteNamed("main.ju", strings.NewReader(input))
+		var rerr RuntimeError
+		if !errors.As(err, &rerr) {
+			t.Fatalf("got error %v instead of runtime error", err)
+		}
+		want := []Frame{
+			{Name: "add", Position: Position{File: "main.ju", Line: 1, Column: 16}},
+			{Name: "inner", Position: Position{File: "main.ju", Line: 3, Column: 9}},
+			{Name: "if", Position: Position{File: "main.ju", Line: 3, Column: 21}},
+			{Name: "outer", Position: Position{File: "main.ju", Line: 5, Column: 1}},
+		}
+		if !reflect.DeepEqual(rerr.Stack, want) {
+			t.Fatalf("got call stack %+v instead of %+v", rerr.Stack, want)
+		}
+		if len(vm.calls) != 0 {
+			t.Fatalf("got %d remaining calls after error", len(vm.calls))
+		}
+	})
+
+	t.Run("reports unknown words", func(t *testing.T) {
+		vm := NewVM()
+		err := vm.Execute(strings.NewReader("[unknown-word] do"))
+		var rerr RuntimeError
+		if !errors.As(err, &rerr) {
+			t.Fatalf("got error %v instead of runtime error", err)
+		}
+		want := []Frame{
+			{Name: "unknown-word", Position: Position{Line: 1, Column: 2}},
+			{Name: "do", Position: Position{Line: 1, Column: 16}},
+		}
+		if !reflect.DeepEqual(rerr.Stack, want) {
+			t.Fatalf("got call stack %+v instead of %+v", rerr.Stack, want)
+		}
+	})
+}
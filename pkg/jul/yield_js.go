@@ -0,0 +1,9 @@
+//go:build js && wasm
+
+package jul
+
+import "runtime"
+
+// yield lets other goroutines run during long executions,
+// as goroutines are not preempted in WebAssembly (so timeouts and cancellations would never be seen).
+func yield() { runtime.Gosched() }
@@ -0,0 +1,6 @@
+//go:build !(js && wasm)
+
+package jul
+
+// yield does nothing as goroutines are preempted on this platform.
+func yield() {}
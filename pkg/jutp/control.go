@@ -0,0 +1,76 @@
+package jutp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ControlPrefix starts control messages.
+// Jul code can't contain this character, so control messages can't be mistaken for code.
+const ControlPrefix = "\x01"
+
+const (
+	CommandHello     = "hello"     // Sent by the client when the connection is opened
+	CommandInstall   = "install"   // Asks the client to install a script that runs periodically
+	CommandUninstall = "uninstall" // Asks the client to remove an installed script
+	CommandSession   = "session"   // Gives the client the token to present when reconnecting (see Session)
+)
+
+// Control is a message exchanged between client and server that is not code to execute.
+//
+// It is encoded as the control prefix followed by the command on the first line,
+// one "key: value" parameter per line, an empty line and the body:
+//
+//	\x01install
+//	name: parcel-status
+//	interval: 5m0s
+//
+//	"Checking your parcel..." write
+type Control struct {
+	Command string
+	Params  map[string]string
+	Body    string
+}
+
+var ErrInvalidControl = errors.New("invalid control message")
+
+func (c Control) Message() Message {
+	var b strings.Builder
+	b.WriteString(ControlPrefix + c.Command + "\n")
+	keys := make([]string, 0, len(c.Params))
+	for k := range c.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(k + ": " + c.Params[k] + "\n")
+	}
+	b.WriteString("\n" + c.Body)
+	return Message(b.String())
+}
+
+// IsControl reports whether the message is a control message rather than code.
+func (msg Message) IsControl() bool { return strings.HasPrefix(string(msg), ControlPrefix) }
+
+// ParseControl decodes a control message.
+func ParseControl(msg Message) (Control, error) {
+	if !msg.IsControl() {
+		return Control{}, fmt.Errorf("%w: missing control prefix", ErrInvalidControl)
+	}
+	head, body, _ := strings.Cut(string(msg[len(ControlPrefix):]), "\n\n")
+	lines := strings.Split(head, "\n")
+	c := Control{Command: lines[0], Params: map[string]string{}, Body: body}
+	if c.Command == "" {
+		return Control{}, fmt.Errorf("%w: missing command", ErrInvalidControl)
+	}
+	for _, line := range lines[1:] {
+		k, v, ok := strings.Cut(line, ": ")
+		if !ok {
+			return Control{}, fmt.Errorf("%w: malformed parameter %q", ErrInvalidControl, line)
+		}
+		c.Params[k] = v
+	}
+	return c, nil
+}
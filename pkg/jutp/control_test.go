@@ -0,0 +1,45 @@
+package jutp
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestControl(t *testing.T) {
+	t.Run("round-trips through a message", func(t *testing.T) {
+		c := Control{
+			Command: CommandInstall,
+			Params:  map[string]string{"name": "status", "interval": "5m0s"},
+			Body:    "\"status\" retrieve\n\n\"done\" write",
+		}
+		msg := c.Message()
+		if !msg.IsControl() {
+			t.Fatalf("message %q is not a control message", msg)
+		}
+		got, err := ParseControl(msg)
+		if err != nil {
+			panic(err)
+		}
+		if !reflect.DeepEqual(got, c) {
+			t.Fatalf("got %+v instead of %+v", got, c)
+		}
+	})
+
+	t.Run("code is not a control message", func(t *testing.T) {
+		_, err := ParseControl(Message(`"Hello" write`))
+		if !errors.Is(err, ErrInvalidControl) {
+			t.Fatalf("got error %v instead of %v", err, ErrInvalidControl)
+		}
+	})
+
+	t.Run("hello holds the script name", func(t *testing.T) {
+		c, err := ParseControl(Hello("status"))
+		if err != nil {
+			panic(err)
+		}
+		if c.Command != CommandHello || c.Params["script"] != "status" {
+			t.Fatalf("got %+v", c)
+		}
+	})
+}
@@ -2,9 +2,12 @@ package jutp
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"fmt"
 	"io"
-	"log"
 	"net"
+	"time"
 )
 
 type Message string
@@ -21,27 +24,116 @@ func Read(r *bufio.Reader) (Message, error) {
 	return Message(msg[:len(msg)-1]), nil
 }
 
+// Serve is like ServeSessions with sessions kept in memory for a day after the last activity.
 func Serve(laddr *net.TCPAddr, handler func(rui *RemoteUI)) error {
-	l, err := net.ListenTCP("tcp", laddr)
+	return ServeSessions(laddr, nil, handler)
+}
+
+// ServeSessions accepts connections and calls the handler for each of them,
+// with the session of the client loaded from (or created in) the given store.
+// It runs until the listener fails, use Server to stop serving.
+func ServeSessions(laddr *net.TCPAddr, store SessionStore, handler func(rui *RemoteUI)) error {
+	srv := &Server{Addr: laddr, Sessions: store, Handler: func(_ context.Context, rui *RemoteUI) { handler(rui) }}
+	return srv.Serve(context.Background())
+}
+
+type RemoteUI struct {
+	conn    *net.TCPConn
+	r       *bufio.Reader
+	hello   Control
+	session *Session
+}
+
+func (rui *RemoteUI) Exec(code string) error { _, err := Write(rui.conn, Message(code)); return err }
+
+func (rui *RemoteUI) Read() (Message, error) {
+	msg, err := Read(rui.r)
+	if err == nil && rui.session != nil {
+		rui.session.Touch()
+	}
+	return msg, err
+}
+
+// Session returns the session of the client, which is resumed when the client reconnects.
+func (rui *RemoteUI) Session() *Session { return rui.session }
+
+// Script returns the name of the installed script the client opened the connection for,
+// it is empty for interactive sessions.
+func (rui *RemoteUI) Script() string { return rui.hello.Params["script"] }
+
+// Install asks the client to persist the given code and execute it periodically.
+// Each execution opens a new connection whose hello message carries the script name (see Script),
+// the server gets data to the script by replying with code on that connection.
+//
+// Installed scripts run without the user, so they can't read input and their uploads (with "retrieve") are refused.
+func (rui *RemoteUI) Install(name string, interval time.Duration, code string) error {
+	_, err := Write(rui.conn, Control{
+		Command: CommandInstall,
+		Params:  map[string]string{"name": name, "interval": interval.String()},
+		Body:    code,
+	}.Message())
+	return err
+}
+
+// Uninstall asks the client to remove a previously installed script.
+func (rui *RemoteUI) Uninstall(name string) error {
+	_, err := Write(rui.conn, Control{Command: CommandUninstall, Params: map[string]string{"name": name}}.Message())
+	return err
+}
+
+func (rui *RemoteUI) readHello() error {
+	msg, err := rui.Read()
+	if err != nil {
+		return fmt.Errorf("read hello: %w", err)
+	}
+	c, err := ParseControl(msg)
 	if err != nil {
-		return err
+		return fmt.Errorf("read hello: %w", err)
 	}
-	defer l.Close()
+	if c.Command != CommandHello {
+		return fmt.Errorf("read hello: %w: got command %q", ErrInvalidControl, c.Command)
+	}
+	rui.hello = c
+	return nil
+}
 
-	for {
-		conn, err := l.AcceptTCP()
-		if err != nil {
-			log.Println(err)
-			continue
+// openSession resumes the session of the token presented in the hello message,
+// or creates a new session and sends its token to the client.
+func (rui *RemoteUI) openSession(store SessionStore) error {
+	if token := rui.hello.Params["session"]; token != "" {
+		s, err := store.Load(token)
+		if err == nil {
+			s.Touch()
+			rui.session = s
+			return nil
+		} else if !errors.Is(err, ErrSessionNotFound) {
+			return fmt.Errorf("load session: %w", err)
 		}
-		go handler(&RemoteUI{conn: conn, r: bufio.NewReader(conn)})
 	}
-}
 
-type RemoteUI struct {
-	conn *net.TCPConn
-	r    *bufio.Reader
+	s := NewSession()
+	err := store.Save(s)
+	if err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	rui.session = s
+	_, err = Write(rui.conn, Control{Command: CommandSession, Params: map[string]string{"token": s.Token}}.Message())
+	return err
 }
 
-func (rui *RemoteUI) Exec(code string) error { _, err := Write(rui.conn, Message(code)); return err }
-func (rui *RemoteUI) Read() (Message, error) { return Read(rui.r) }
+// Hello returns the message sent by clients when opening a connection.
+// The script name is set when the connection is opened to run an installed script.
+func Hello(script string) Message { return HelloWithToken(script, "") }
+
+// HelloWithToken is like Hello but resumes the session of the given token (if not empty),
+// clients receive their token in a "session" control message.
+func HelloWithToken(script, token string) Message {
+	c := Control{Command: CommandHello, Params: map[string]string{}}
+	if script != "" {
+		c.Params["script"] = script
+	}
+	if token != "" {
+		c.Params["session"] = token
+	}
+	return c.Message()
+}
@@ -0,0 +1,212 @@
+package jutp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed is returned by Server.Serve after a call to Shutdown.
+var ErrServerClosed = errors.New("jutp: server closed")
+
+// helloTimeout is how long clients have to send the hello message after connecting.
+const helloTimeout = 10 * time.Second
+
+// Server accepts JuTP connections and calls the handler for each of them.
+type Server struct {
+	Addr     *net.TCPAddr
+	Handler  func(ctx context.Context, rui *RemoteUI)
+	Sessions SessionStore // Defaults to sessions kept in memory for a day after the last activity
+	Logger   *log.Logger  // Defaults to log.Default()
+
+	mu        sync.Mutex
+	listeners map[*net.TCPListener]struct{}
+	conns     map[*net.TCPConn]context.CancelFunc
+	closing   bool
+	handlers  sync.WaitGroup
+}
+
+// Serve listens on the server address and handles connections until Shutdown is called
+// (then ErrServerClosed is returned) or until the context is done.
+//
+// Each handler gets a context that is cancelled when the connection is closed by the server,
+// which is the case when the context of Serve is done (active connections are closed right away)
+// or when Shutdown gives up waiting for active handlers. The connection is closed when the handler returns.
+func (srv *Server) Serve(ctx context.Context) error {
+	l, err := net.ListenTCP("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return srv.serve(ctx, l)
+}
+
+// Shutdown stops accepting connections and waits for active handlers to return.
+// If the context is done first, active connections are closed and the context error is returned.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	srv.mu.Lock()
+	srv.closing = true
+	for l := range srv.listeners {
+		l.Close()
+	}
+	srv.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		srv.handlers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.closeConns()
+		return ctx.Err()
+	}
+}
+
+func (srv *Server) serve(ctx context.Context, l *net.TCPListener) error {
+	defer l.Close()
+	srv.mu.Lock()
+	if srv.closing {
+		srv.mu.Unlock()
+		return ErrServerClosed
+	}
+	if srv.listeners == nil {
+		srv.listeners = map[*net.TCPListener]struct{}{}
+		srv.conns = map[*net.TCPConn]context.CancelFunc{}
+	}
+	if srv.Sessions == nil {
+		srv.Sessions = NewMemorySessionStore(24 * time.Hour)
+	}
+	srv.listeners[l] = struct{}{}
+	srv.mu.Unlock()
+	defer func() {
+		srv.mu.Lock()
+		delete(srv.listeners, l)
+		srv.mu.Unlock()
+	}()
+
+	// Stop accepting connections when the context is done
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.Close()
+		case <-stop:
+		}
+	}()
+
+	var delay time.Duration
+	for {
+		conn, err := l.AcceptTCP()
+		if err != nil {
+			if srv.isClosing() {
+				return ErrServerClosed
+			} else if ctx.Err() != nil {
+				srv.closeConns()
+				srv.handlers.Wait()
+				return ctx.Err()
+			} else if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+
+			// Retry later on other errors (like too many open files)
+			delay *= 2
+			if delay == 0 {
+				delay = 5 * time.Millisecond
+			} else if delay > time.Second {
+				delay = time.Second
+			}
+			srv.logger().Printf("accept: %s, retrying in %s", err, delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		delay = 0
+		srv.handle(ctx, conn)
+	}
+}
+
+// handle calls the handler in a new goroutine, after reading the hello message and loading the session.
+func (srv *Server) handle(ctx context.Context, conn *net.TCPConn) {
+	ctx, cancel := context.WithCancel(ctx)
+	srv.mu.Lock()
+	if srv.closing {
+		srv.mu.Unlock()
+		cancel()
+		conn.Close()
+		return
+	}
+	srv.conns[conn] = cancel
+	srv.handlers.Add(1)
+	sessions := srv.Sessions
+	srv.mu.Unlock()
+
+	go func() {
+		defer srv.handlers.Done()
+		defer func() {
+			cancel()
+			conn.Close()
+			srv.mu.Lock()
+			delete(srv.conns, conn)
+			srv.mu.Unlock()
+		}()
+
+		rui := &RemoteUI{conn: conn, r: bufio.NewReader(conn)}
+		err := conn.SetReadDeadline(time.Now().Add(helloTimeout))
+		if err != nil {
+			return
+		}
+		err = rui.readHello()
+		if err != nil {
+			srv.logger().Println(err)
+			return
+		}
+		err = conn.SetReadDeadline(time.Time{})
+		if err != nil {
+			return
+		}
+		err = rui.openSession(sessions)
+		if err != nil {
+			srv.logger().Println(err)
+			return
+		}
+		defer func() {
+			err := sessions.Save(rui.session)
+			if err != nil {
+				srv.logger().Printf("save session %s: %s", rui.session.ID, err)
+			}
+		}()
+		srv.Handler(ctx, rui)
+	}()
+}
+
+func (srv *Server) isClosing() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.closing
+}
+
+// closeConns closes all active connections and cancels their context.
+func (srv *Server) closeConns() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for conn, cancel := range srv.conns {
+		cancel()
+		conn.Close()
+	}
+}
+
+func (srv *Server) logger() *log.Logger {
+	if srv.Logger == nil {
+		return log.Default()
+	}
+	return srv.Logger
+}
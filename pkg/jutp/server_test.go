@@ -0,0 +1,139 @@
+package jutp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"testing"
+	"time"
+)
+
+// startServer serves connections with the given handler, Serve returns its error on the returned channel.
+func startServer(ctx context.Context, handler func(ctx context.Context, rui *RemoteUI)) (*Server, string, chan error) {
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		panic(err)
+	}
+	srv := &Server{Handler: handler, Logger: log.New(io.Discard, "", 0)}
+	done := make(chan error, 1)
+	go func() { done <- srv.serve(ctx, l) }()
+	return srv, l.Addr().String(), done
+}
+
+// connect opens a connection, sends the hello message and reads the session token.
+func connect(addr string) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		panic(err)
+	}
+	_, err = Write(conn, Hello(""))
+	if err != nil {
+		panic(err)
+	}
+	r := bufio.NewReader(conn)
+	_, err = Read(r)
+	if err != nil {
+		panic(err)
+	}
+	return conn, r
+}
+
+func TestServer(t *testing.T) {
+	t.Run("waits for active handlers on shutdown", func(t *testing.T) {
+		started := make(chan struct{})
+		srv, addr, done := startServer(context.Background(), func(ctx context.Context, rui *RemoteUI) {
+			close(started)
+			msg, err := rui.Read()
+			if err == nil {
+				_ = rui.Exec("bye " + string(msg))
+			}
+		})
+		conn, r := connect(addr)
+		defer conn.Close()
+		<-started
+
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- srv.Shutdown(context.Background()) }()
+		if err := <-done; !errors.Is(err, ErrServerClosed) {
+			t.Fatalf("got error %v instead of %v", err, ErrServerClosed)
+		}
+		if _, err := net.Dial("tcp", addr); err == nil {
+			t.Fatal("server still accepts connections")
+		}
+		select {
+		case err := <-shutdown:
+			t.Fatalf("shutdown returned %v before the handler", err)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		// The handler completes and its connection is closed
+		_, err := Write(conn, "Ju")
+		if err != nil {
+			panic(err)
+		}
+		if msg, err := Read(r); err != nil || msg != "bye Ju" {
+			t.Fatalf("got message %q and error %v", msg, err)
+		}
+		if err := <-shutdown; err != nil {
+			t.Fatalf("got error %v", err)
+		}
+		if _, err := Read(r); !errors.Is(err, io.EOF) {
+			t.Fatalf("got error %v instead of %v", err, io.EOF)
+		}
+	})
+
+	t.Run("closes connections when the shutdown deadline is reached", func(t *testing.T) {
+		cancelled := make(chan struct{})
+		srv, addr, _ := startServer(context.Background(), func(ctx context.Context, rui *RemoteUI) {
+			_, _ = rui.Read()
+			<-ctx.Done()
+			close(cancelled)
+		})
+		conn, _ := connect(addr)
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got error %v instead of %v", err, context.DeadlineExceeded)
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("handler context was not cancelled")
+		}
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, addr, done := startServer(ctx, func(ctx context.Context, rui *RemoteUI) {
+			_, _ = rui.Read()
+		})
+		conn, _ := connect(addr)
+		defer conn.Close()
+
+		cancel()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("got error %v instead of %v", err, context.Canceled)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("serve did not return")
+		}
+	})
+
+	t.Run("refuses to serve after shutdown", func(t *testing.T) {
+		srv := &Server{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}}
+		err := srv.Shutdown(context.Background())
+		if err != nil {
+			panic(err)
+		}
+		if err := srv.Serve(context.Background()); !errors.Is(err, ErrServerClosed) {
+			t.Fatalf("got error %v instead of %v", err, ErrServerClosed)
+		}
+	})
+}
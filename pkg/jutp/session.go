@@ -0,0 +1,197 @@
+package jutp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound is returned by session stores when no session has the given token.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is the server-side state of a client, kept across connections:
+// the client receives a token when the session is created and presents it when reconnecting (see HelloWithToken).
+//
+// Attributes can be used by handlers to store the state of the conversation, they are safe for concurrent use
+// as a client may have several connections open (for example when running installed scripts).
+type Session struct {
+	ID        string // Unique identifier, that can be logged (unlike the token)
+	Token     string // Secret presented by the client to resume the session
+	CreatedAt time.Time
+
+	mu         sync.Mutex
+	lastActive time.Time
+	attrs      map[string]string
+}
+
+// NewSession returns a session with a random ID and token.
+func NewSession() *Session {
+	now := time.Now()
+	return &Session{
+		ID:         randomHex(16),
+		Token:      randomHex(32),
+		CreatedAt:  now,
+		lastActive: now,
+		attrs:      map[string]string{},
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Get returns the value of an attribute.
+func (s *Session) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.attrs[key]
+	return v, ok
+}
+
+// Set sets the value of an attribute.
+func (s *Session) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs[key] = value
+}
+
+// Delete removes an attribute.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attrs, key)
+}
+
+// Keys returns the sorted names of all attributes.
+func (s *Session) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.attrs))
+	for k := range s.attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LastActive returns the last time the client connected or sent a message.
+func (s *Session) LastActive() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastActive
+}
+
+// Touch sets the last activity time to now.
+func (s *Session) Touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = time.Now()
+}
+
+// sessionJSON is the encoding of sessions, for stores that persist them.
+type sessionJSON struct {
+	ID         string            `json:"id"`
+	Token      string            `json:"token"`
+	CreatedAt  time.Time         `json:"created_at"`
+	LastActive time.Time         `json:"last_active"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+func (s *Session) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(sessionJSON{
+		ID:         s.ID,
+		Token:      s.Token,
+		CreatedAt:  s.CreatedAt,
+		LastActive: s.lastActive,
+		Attributes: s.attrs,
+	})
+}
+
+func (s *Session) UnmarshalJSON(b []byte) error {
+	var v sessionJSON
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	if v.Attributes == nil {
+		v.Attributes = map[string]string{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ID, s.Token, s.CreatedAt, s.lastActive, s.attrs = v.ID, v.Token, v.CreatedAt, v.LastActive, v.Attributes
+	return nil
+}
+
+// SessionStore keeps sessions between connections, it must be safe for concurrent use.
+type SessionStore interface {
+	Load(token string) (*Session, error) // Returns ErrSessionNotFound if no session has the token
+	Save(s *Session) error               // Called when a session is created and when a connection is closed
+	Delete(token string) error
+}
+
+// MemorySessionStore keeps sessions in memory, sessions are lost when the server restarts.
+type MemorySessionStore struct {
+	maxIdle time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewMemorySessionStore returns a store that forgets sessions inactive for longer than maxIdle (0 means never).
+func NewMemorySessionStore(maxIdle time.Duration) *MemorySessionStore {
+	return &MemorySessionStore{maxIdle: maxIdle, sessions: map[string]*Session{}}
+}
+
+func (store *MemorySessionStore) Load(token string) (*Session, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	s, ok := store.sessions[token]
+	if !ok || store.expired(s, time.Now()) {
+		delete(store.sessions, token)
+		return nil, ErrSessionNotFound
+	}
+	return s, nil
+}
+
+// Save adds the session to the store and removes expired sessions.
+func (store *MemorySessionStore) Save(s *Session) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	now := time.Now()
+	for token, other := range store.sessions {
+		if store.expired(other, now) {
+			delete(store.sessions, token)
+		}
+	}
+	store.sessions[s.Token] = s
+	return nil
+}
+
+func (store *MemorySessionStore) Delete(token string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.sessions, token)
+	return nil
+}
+
+// Len returns the number of sessions in the store (including expired sessions that were not removed yet).
+func (store *MemorySessionStore) Len() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return len(store.sessions)
+}
+
+func (store *MemorySessionStore) expired(s *Session, now time.Time) bool {
+	return store.maxIdle > 0 && now.Sub(s.LastActive()) > store.maxIdle
+}
@@ -0,0 +1,132 @@
+package jutp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// startSessionServer serves connections with a handler that replies with the previous message of the session.
+func startSessionServer(t *testing.T, store SessionStore) string {
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		panic(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	srv := &Server{Sessions: store, Handler: func(_ context.Context, rui *RemoteUI) {
+		msg, err := rui.Read()
+		if err != nil {
+			return
+		}
+		prev, _ := rui.Session().Get("previous")
+		rui.Session().Set("previous", string(msg))
+		_ = rui.Exec(prev)
+	}}
+	go srv.serve(context.Background(), l)
+	return l.Addr().String()
+}
+
+// exchange connects with the given token, sends a message and returns the session token and reply.
+func exchange(t *testing.T, addr, token string, msg Message) (string, Message) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	_, err = Write(conn, HelloWithToken("", token))
+	if err != nil {
+		panic(err)
+	}
+	_, err = Write(conn, msg)
+	if err != nil {
+		panic(err)
+	}
+	reply, err := Read(r)
+	if err != nil {
+		panic(err)
+	}
+	if reply.IsControl() {
+		c, err := ParseControl(reply)
+		if err != nil {
+			panic(err)
+		}
+		if c.Command != CommandSession {
+			t.Fatalf("got command %q instead of %q", c.Command, CommandSession)
+		}
+		token = c.Params["token"]
+		reply, err = Read(r)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return token, reply
+}
+
+func TestSessions(t *testing.T) {
+	t.Run("resumes sessions when the client presents its token", func(t *testing.T) {
+		store := NewMemorySessionStore(0)
+		addr := startSessionServer(t, store)
+
+		token, reply := exchange(t, addr, "", "a")
+		if token == "" || reply != "" {
+			t.Fatalf("got token %q and reply %q for a new session", token, reply)
+		}
+		token2, reply := exchange(t, addr, token, "b")
+		if token2 != token || reply != "a" {
+			t.Fatalf("got token %q and reply %q instead of %q and %q", token2, reply, token, "a")
+		}
+
+		// Unknown tokens get a new session
+		token3, reply := exchange(t, addr, "unknown", "c")
+		if token3 == "" || token3 == token || reply != "" {
+			t.Fatalf("got token %q and reply %q for an unknown token", token3, reply)
+		}
+		if store.Len() != 2 {
+			t.Fatalf("got %d sessions instead of 2", store.Len())
+		}
+	})
+
+	t.Run("forgets inactive sessions", func(t *testing.T) {
+		store := NewMemorySessionStore(time.Minute)
+		s := NewSession()
+		err := store.Save(s)
+		if err != nil {
+			panic(err)
+		}
+		if _, err := store.Load(s.Token); err != nil {
+			t.Fatalf("got error %v for an active session", err)
+		}
+		s.lastActive = time.Now().Add(-2 * time.Minute)
+		if _, err := store.Load(s.Token); err != ErrSessionNotFound {
+			t.Fatalf("got error %v instead of %v", err, ErrSessionNotFound)
+		}
+		if store.Len() != 0 {
+			t.Fatalf("got %d sessions instead of 0", store.Len())
+		}
+	})
+
+	t.Run("round-trips through JSON", func(t *testing.T) {
+		s := NewSession()
+		s.Set("name", "Ju")
+		b, err := json.Marshal(s)
+		if err != nil {
+			panic(err)
+		}
+		got := &Session{}
+		err = json.Unmarshal(b, got)
+		if err != nil {
+			panic(err)
+		}
+		if got.ID != s.ID || got.Token != s.Token || !got.CreatedAt.Equal(s.CreatedAt) || !got.LastActive().Equal(s.LastActive()) {
+			t.Fatalf("got session %+v instead of %+v", got, s)
+		}
+		if !reflect.DeepEqual(got.Keys(), []string{"name"}) {
+			t.Fatalf("got attributes %q instead of %q", got.Keys(), []string{"name"})
+		}
+	})
+}
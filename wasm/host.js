@@ -0,0 +1,75 @@
+// Host page of the WebAssembly build: scripts are executed in the browser,
+// messages are exchanged with the server through the HTTP gateway.
+"use strict";
+
+const output = document.getElementById("output");
+const form = document.getElementById("input");
+const field = form.querySelector("input");
+const statusText = document.getElementById("status");
+
+// ui implements the methods used by jul.JSUI, formatted messages and menus fall back to plain text.
+const ui = {
+    write(text) {
+        output.append(text);
+        field.scrollIntoView({ block: "end" });
+    },
+    read() {
+        return new Promise((resolve) => {
+            form.hidden = false;
+            field.focus();
+            form.onsubmit = (e) => {
+                e.preventDefault();
+                const line = field.value;
+                field.value = "";
+                form.hidden = true;
+                output.append(line + "\n");
+                resolve(line);
+            };
+        });
+    },
+    confirmUpload(u) {
+        return window.confirm(`Send this to ${u.origin}?\n\n${u.data}`);
+    },
+    askPermission(origin, description) {
+        return window.confirm(`Allow ${origin} to use ${description}?`);
+    },
+};
+
+main().catch((err) => (statusText.textContent = err.message));
+
+async function main() {
+    const go = new Go();
+    const { instance } = await WebAssembly.instantiateStreaming(fetch("jul.wasm"), go.importObject);
+    go.run(instance);
+
+    const res = await fetch("api/sessions", { method: "POST" });
+    if (!res.ok) {
+        throw new Error("Unable to connect: " + (await res.text()));
+    }
+    const sessionURL = "api/sessions/" + encodeURIComponent((await res.json()).id);
+    const transport = {
+        async send(msg) {
+            const res = await fetch(sessionURL + "/messages", { method: "POST", body: msg });
+            if (!res.ok) {
+                throw new Error(await res.text());
+            }
+        },
+        close() {
+            fetch(sessionURL, { method: "DELETE", keepalive: true });
+        },
+    };
+    await transport.send(jul.hello);
+
+    const session = jul.connect(ui, transport, location.host);
+    const events = new EventSource(sessionURL + "/events");
+    events.onmessage = (e) => session.receive(JSON.parse(e.data));
+    events.addEventListener("close", () => {
+        events.close();
+        session.close();
+    });
+    statusText.textContent = "";
+
+    await session.done;
+    form.hidden = true;
+    statusText.textContent = "The conversation has ended.";
+}
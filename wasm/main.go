@@ -0,0 +1,80 @@
+//go:build js && wasm
+
+// Command wasm runs Jul scripts in the browser, it defines a global "jul" object with:
+//   - connect(ui, transport, origin), which starts a session (see jul.JSUI and jul.JSConn) and returns
+//     an object with receive(message) to execute messages received from the server,
+//     close() to end the session and done, a promise resolved when the session ends.
+//   - hello, the message to send to the server when opening a connection.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o wasm/jul.wasm ./wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" wasm/
+//
+// index.html is a host page that talks to the server through the HTTP gateway (see juhttp.Gateway),
+// run the demo with "-ui wasm" to serve it.
+package main
+
+import (
+	"log"
+	"syscall/js"
+
+	"github.com/ejuju/jus/pkg/juclient"
+	"github.com/ejuju/jus/pkg/jul"
+	"github.com/ejuju/jus/pkg/jutp"
+)
+
+func main() {
+	js.Global().Set("jul", js.ValueOf(map[string]any{
+		"connect": js.FuncOf(connect),
+		"hello":   string(jutp.Hello("")),
+	}))
+	select {}
+}
+
+func connect(this js.Value, args []js.Value) any {
+	if len(args) != 3 {
+		panic("connect: expected ui, transport and origin")
+	}
+	origin := args[2].String()
+	conn := jul.NewJSConn(args[1], origin)
+	session := juclient.NewSession(conn, jul.NewJSUI(args[0]), ignoreControl,
+		jul.WithOrigin(origin),
+		jul.WithPermissions(jul.NewPermissions(origin, jul.NewMemoryStore())),
+		jul.WithMaxSteps(10_000_000),
+		jul.WithMaxMemory(16<<20),
+	)
+
+	// Run the session outside of the JavaScript event loop, as the UI and transport may return promises
+	done := js.Global().Get("Promise").New(js.FuncOf(func(this js.Value, args []js.Value) any {
+		resolve, reject := args[0], args[1]
+		go func() {
+			defer conn.Close()
+			err := session.Run()
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			resolve.Invoke()
+		}()
+		return nil
+	}))
+
+	return js.ValueOf(map[string]any{
+		"receive": js.FuncOf(func(this js.Value, args []js.Value) any {
+			conn.Receive(args[0].String())
+			return nil
+		}),
+		"close": js.FuncOf(func(this js.Value, args []js.Value) any {
+			conn.Close()
+			return nil
+		}),
+		"done": done,
+	})
+}
+
+// ignoreControl ignores scripts installed by the server, as they can't run periodically in a browser page.
+func ignoreControl(_ jul.UI, c jutp.Control) error {
+	log.Printf("ignored control message %q", c.Command)
+	return nil
+}